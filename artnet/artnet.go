@@ -0,0 +1,119 @@
+//Package artnet receives Art-Net packets and writes the DMX data to the strips of a rpiws281x.Config.
+package artnet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net"
+)
+
+// Errors
+var (
+	ErrWrongPortAddress = errors.New("net has to be 0 to 127, subnet and universe 0 to 15")
+	ErrWrongPatch       = errors.New("patch outside of the strip")
+	ErrNotWritable      = errors.New("strip is not writable")
+	ErrNoPatches        = errors.New("no patches")
+)
+
+const (
+	Port         = 6454 // UDP port of Art-Net
+	UniverseSize = 512  // DMX channels of one universe
+
+	opPoll      = 0x2000
+	opPollReply = 0x2100
+	opDmx       = 0x5000
+	opSync      = 0x5200
+
+	protocolVersion = 14
+	headerSize      = 12 // ID, opcode and protocol version
+	dmxHeaderSize   = 18 // Bytes before the first DMX channel
+	pollReplySize   = 239
+	portsPerReply   = 4 // Ports of one ArtPollReply
+	maxNet          = 0x7f
+	maxSubNet       = 0xf
+	maxUniverse     = 0xf
+)
+
+var packetID = []byte("Art-Net\x00")
+
+//ArtDmx packet
+type dmxPacket struct {
+	sequence    uint8
+	portAddress uint16
+	data        []byte
+}
+
+//returns the opcode of packet. 0 if the packet is not Art-Net
+func opCode(packet []byte) uint16 {
+	if len(packet) < headerSize || !bytes.Equal(packet[:len(packetID)], packetID) {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(packet[8:])
+}
+
+//parses an ArtDmx packet. ok is false if the packet is not valid
+func parseDmxPacket(packet []byte) (res dmxPacket, ok bool) {
+	if len(packet) < dmxHeaderSize || opCode(packet) != opDmx {
+		return res, false
+	}
+	length := int(binary.BigEndian.Uint16(packet[16:]))
+	if length > UniverseSize || dmxHeaderSize+length > len(packet) {
+		return res, false
+	}
+	res.sequence = packet[12]
+	//SubUni holds the subnet and the universe. Net holds the upper 7 bits
+	res.portAddress = uint16(packet[15]&maxNet)<<8 | uint16(packet[14])
+	res.data = packet[dmxHeaderSize : dmxHeaderSize+length]
+	return res, true
+}
+
+//returns the 15 bit port address
+func portAddress(netID, subNet, universe uint8) uint16 {
+	return uint16(netID)<<8 | uint16(subNet)<<4 | uint16(universe)
+}
+
+//returns true if sequence is older than last. Sequence 0 disables the check
+func sequenceOld(sequence, last uint8) bool {
+	if sequence == 0 || last == 0 {
+		return false
+	}
+	diff := int8(sequence - last)
+	return diff <= 0 && diff > -20
+}
+
+//node information of an ArtPollReply
+type node struct {
+	ip        net.IP
+	mac       net.HardwareAddr
+	shortName string
+	longName  string
+}
+
+//builds an ArtPollReply for up to 4 port addresses which share the net and subnet. bindIndex numbers the replies from 1.
+func pollReply(n node, ports []uint16, bindIndex int) []byte {
+	res := make([]byte, pollReplySize)
+	copy(res, packetID)
+	binary.LittleEndian.PutUint16(res[8:], opPollReply)
+	if ip := n.ip.To4(); ip != nil {
+		copy(res[10:14], ip)
+		copy(res[207:211], ip)
+	}
+	binary.LittleEndian.PutUint16(res[14:], Port)
+	res[18] = uint8(ports[0] >> 8)           // Net
+	res[19] = uint8(ports[0]>>4) & maxSubNet // SubNet
+	res[23] = 0xc0                           // Indicators normal
+	copy(res[26:43], n.shortName)
+	copy(res[44:107], n.longName)
+	copy(res[108:171], "#0001 [0000] Ok")
+	binary.BigEndian.PutUint16(res[172:], uint16(len(ports)))
+	for i, curPort := range ports {
+		res[174+i] = 0x80 // Output of DMX512
+		res[182+i] = 0x80 // Data is output
+		res[190+i] = uint8(curPort) & maxUniverse
+	}
+	copy(res[201:207], n.mac)
+	res[211] = uint8(bindIndex)
+	res[212] = 0x08 // 15 bit port addresses
+	return res
+}
@@ -0,0 +1,263 @@
+package artnet
+
+import (
+	"context"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/DerLukas15/rpiws281x"
+	"github.com/DerLukas15/rpiws281x/internal/receive"
+	"github.com/pkg/errors"
+)
+
+const syncTimeout = 4 * time.Second // Without ArtSync for this time the data is rendered without sync again
+
+//Patch maps a universe onto the strip of a Config.
+type Patch struct {
+	Net        uint8 // 0 to 127
+	SubNet     uint8 // 0 to 15
+	Universe   uint8 // 0 to 15
+	StripIndex int   // Strip set with SetStrip. Its StripType selects 3 or 4 slots per LED
+	Offset     int   // Position of the first LED on the strip
+	Count      int   // Number of LEDs. 0 for all LEDs the universe holds
+}
+
+//a Patch with the values resolved from the Config
+type patch struct {
+	Patch
+	leds       rpiws281x.WritableLEDs
+	colorCount int
+}
+
+//state of a patched universe
+type universe struct {
+	patches  []patch
+	sequence uint8
+	waiting  bool // Data was written which is not rendered yet
+}
+
+//Receiver writes the data of ArtDmx packets to the strips of a Config and renders them.
+/*
+Once an ArtSync is received, data is only rendered with the next ArtSync. If no ArtSync is received for 4 seconds, data
+is rendered once every patched universe was updated or if a universe is updated again before that.
+Data of several controllers is not merged, the last packet wins.
+
+ArtPoll is answered with one ArtPollReply per 4 patched universes of the same net and subnet.
+
+A Receiver is not safe for concurrent use. Render is called from the goroutine handling the packets.
+*/
+type Receiver struct {
+	config    *rpiws281x.Config
+	universes map[uint16]*universe
+	ports     []uint16 // Sorted patched port addresses
+	node      node
+	lastSync  time.Time
+}
+
+//NewReceiver returns a Receiver writing to the strips of config as set by patches. The strips have to be set with
+//SetStrip before.
+func NewReceiver(config *rpiws281x.Config, patches []Patch) (*Receiver, error) {
+	if len(patches) == 0 {
+		return nil, errors.Wrap(ErrNoPatches, "new receiver")
+	}
+	r := &Receiver{
+		config:    config,
+		universes: make(map[uint16]*universe),
+		node: node{
+			shortName: "rpiws281x",
+			longName:  "rpiws281x Art-Net node",
+		},
+	}
+	for _, curPatch := range patches {
+		if curPatch.Net > maxNet || curPatch.SubNet > maxSubNet || curPatch.Universe > maxUniverse {
+			return nil, errors.Wrap(ErrWrongPortAddress, "new receiver")
+		}
+		strip, stripType, err := config.Strip(curPatch.StripIndex)
+		if err != nil {
+			return nil, errors.Wrap(err, "new receiver")
+		}
+		leds, ok := strip.(rpiws281x.WritableLEDs)
+		if !ok {
+			return nil, errors.Wrap(ErrNotWritable, "new receiver")
+		}
+		colorCount := stripType.ColorCount()
+		maxCount := UniverseSize / colorCount
+		if curPatch.Count == 0 {
+			curPatch.Count = maxCount
+			if curPatch.Offset+curPatch.Count > leds.TotalCount() {
+				curPatch.Count = leds.TotalCount() - curPatch.Offset
+			}
+		}
+		if curPatch.Offset < 0 || curPatch.Count <= 0 || curPatch.Count > maxCount ||
+			curPatch.Offset+curPatch.Count > leds.TotalCount() {
+			return nil, errors.Wrap(ErrWrongPatch, "new receiver")
+		}
+		address := portAddress(curPatch.Net, curPatch.SubNet, curPatch.Universe)
+		curUniverse, ok := r.universes[address]
+		if !ok {
+			curUniverse = &universe{}
+			r.universes[address] = curUniverse
+			r.ports = append(r.ports, address)
+		}
+		curUniverse.patches = append(curUniverse.patches, patch{Patch: curPatch, leds: leds, colorCount: colorCount})
+	}
+	sort.Slice(r.ports, func(i, j int) bool { return r.ports[i] < r.ports[j] })
+	return r, nil
+}
+
+//SetNodeName sets the names sent in ArtPollReply. The short name is cut to 17 and the long name to 63 characters.
+func (r *Receiver) SetNodeName(shortName, longName string) {
+	r.node.shortName = shortName
+	r.node.longName = longName
+}
+
+//SetNodeAddress sets the addresses sent in ArtPollReply. ListenAndServe uses the addresses of its interface if they are not set.
+func (r *Receiver) SetNodeAddress(ip net.IP, mac net.HardwareAddr) {
+	r.node.ip = ip
+	r.node.mac = mac
+}
+
+//Handle processes one Art-Net packet received from addr. ArtPoll is answered on conn. Packets which are not Art-Net
+//or not for a patched universe are ignored.
+func (r *Receiver) Handle(conn net.PacketConn, addr net.Addr, packet []byte) error {
+	switch opCode(packet) {
+	case opDmx:
+		dmx, ok := parseDmxPacket(packet)
+		if !ok {
+			return nil
+		}
+		return r.handleDmx(dmx, time.Now())
+	case opSync:
+		r.lastSync = time.Now()
+		return r.render()
+	case opPoll:
+		return r.replyPoll(conn, addr)
+	}
+	return nil
+}
+
+//writes the data to the patches of the universe and renders if the frame is complete
+func (r *Receiver) handleDmx(dmx dmxPacket, now time.Time) error {
+	curUniverse, ok := r.universes[dmx.portAddress]
+	if !ok || sequenceOld(dmx.sequence, curUniverse.sequence) {
+		return nil
+	}
+	curUniverse.sequence = dmx.sequence
+	synced := now.Sub(r.lastSync) <= syncTimeout
+	//Show the previous frame of this universe before it is overwritten
+	if curUniverse.waiting && !synced {
+		err := r.render()
+		if err != nil {
+			return err
+		}
+	}
+	for _, curPatch := range curUniverse.patches {
+		last := curPatch.Count * curPatch.colorCount
+		if last > len(dmx.data) {
+			last = len(dmx.data)
+		}
+		rpiws281x.SetSlots(curPatch.leds, curPatch.Offset, dmx.data[:last], curPatch.colorCount)
+	}
+	curUniverse.waiting = true
+	if synced {
+		return nil
+	}
+	for _, otherUniverse := range r.universes {
+		if !otherUniverse.waiting {
+			return nil
+		}
+	}
+	return r.render()
+}
+
+//renders the strips of all waiting universes
+func (r *Receiver) render() error {
+	var set receive.RenderSet
+	for _, curUniverse := range r.universes {
+		if !curUniverse.waiting {
+			continue
+		}
+		curUniverse.waiting = false
+		for _, curPatch := range curUniverse.patches {
+			set.Add(curPatch.StripIndex)
+		}
+	}
+	err := set.Render(r.config)
+	if err != nil {
+		return errors.Wrap(err, "receiver render")
+	}
+	return nil
+}
+
+//sends one ArtPollReply per group of 4 ports with the same net and subnet to addr
+func (r *Receiver) replyPoll(conn net.PacketConn, addr net.Addr) error {
+	n := r.node
+	if n.ip == nil {
+		if localAddr, ok := conn.LocalAddr().(*net.UDPAddr); ok && !localAddr.IP.IsUnspecified() {
+			n.ip = localAddr.IP
+		}
+	}
+	bindIndex := 1
+	for first := 0; first < len(r.ports); {
+		last := first + 1
+		for last < len(r.ports) && last-first < portsPerReply && r.ports[last]>>4 == r.ports[first]>>4 {
+			last++
+		}
+		_, err := conn.WriteTo(pollReply(n, r.ports[first:last], bindIndex), addr)
+		if err != nil {
+			return errors.Wrap(err, "receiver poll reply")
+		}
+		bindIndex++
+		first = last
+	}
+	return nil
+}
+
+//Serve handles the packets received on conn until ctx is done or an error occurs. conn is not closed.
+//A done ctx is not an error.
+func (r *Receiver) Serve(ctx context.Context, conn net.PacketConn) error {
+	stop := receive.UnblockReads(ctx, conn)
+	defer stop()
+	buf := make([]byte, 1500)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "receiver serve")
+		}
+		err = r.Handle(conn, addr, buf[:n])
+		if err != nil {
+			return errors.Wrap(err, "receiver serve")
+		}
+	}
+}
+
+//ListenAndServe listens on the Art-Net port for broadcast and unicast packets and handles them until ctx is done or
+//an error occurs. The address of ifi is sent in ArtPollReply if not set with SetNodeAddress. ifi may be nil.
+//A done ctx is not an error.
+func (r *Receiver) ListenAndServe(ctx context.Context, ifi *net.Interface) error {
+	if ifi != nil && r.node.ip == nil {
+		addrs, err := ifi.Addrs()
+		if err != nil {
+			return errors.Wrap(err, "receiver listen")
+		}
+		for _, curAddr := range addrs {
+			if ipNet, ok := curAddr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+				r.node.ip = ipNet.IP
+				break
+			}
+		}
+		if r.node.mac == nil {
+			r.node.mac = ifi.HardwareAddr
+		}
+	}
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{Port: Port})
+	if err != nil {
+		return errors.Wrap(err, "receiver listen")
+	}
+	defer conn.Close()
+	return r.Serve(ctx, conn)
+}
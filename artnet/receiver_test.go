@@ -0,0 +1,236 @@
+package artnet
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/DerLukas15/rpiws281x"
+	"github.com/DerLukas15/rpiws281x/internal/receivetest"
+)
+
+//builds an ArtDmx packet
+func buildDmx(sequence uint8, address uint16, data []byte) []byte {
+	res := make([]byte, dmxHeaderSize+len(data))
+	copy(res, packetID)
+	binary.LittleEndian.PutUint16(res[8:], opDmx)
+	res[11] = protocolVersion
+	res[12] = sequence
+	res[14] = uint8(address)
+	res[15] = uint8(address >> 8)
+	binary.BigEndian.PutUint16(res[16:], uint16(len(data)))
+	copy(res[dmxHeaderSize:], data)
+	return res
+}
+
+//builds a packet without data like ArtSync and ArtPoll
+func buildOp(op uint16) []byte {
+	res := make([]byte, 14)
+	copy(res, packetID)
+	binary.LittleEndian.PutUint16(res[8:], op)
+	res[11] = protocolVersion
+	return res
+}
+
+//returns a sender and a receiver on the loopback interface
+func loopback(t *testing.T) (sender net.PacketConn, receiver net.PacketConn) {
+	t.Helper()
+	var conns [2]net.PacketConn
+	for i := range conns {
+		conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		conns[i] = conn
+	}
+	return conns[0], conns[1]
+}
+
+//sends packet over the loopback interface and handles it with r
+func deliver(t *testing.T, r *Receiver, sender net.PacketConn, receiver net.PacketConn, packet []byte) {
+	t.Helper()
+	data, addr := receivetest.Transfer(t, sender, receiver, packet)
+	err := r.Handle(receiver, addr, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+//returns a Receiver with 1:2:0 on the first two LEDs of strip 0, 1:2:1 on the last two LEDs of strip 0 and 0:0:5 on strip 1
+func newTestReceiver(t *testing.T, config *rpiws281x.Config) *Receiver {
+	t.Helper()
+	r, err := NewReceiver(config, []Patch{
+		{Net: 1, SubNet: 2, Universe: 0, StripIndex: 0, Count: 2},
+		{Net: 1, SubNet: 2, Universe: 1, StripIndex: 0, Offset: 3},
+		{Net: 0, SubNet: 0, Universe: 5, StripIndex: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestNewReceiver(t *testing.T) {
+	setup := receivetest.NewSetup(t)
+	r := newTestReceiver(t, setup.Config)
+	if len(r.ports) != 3 || r.ports[0] != 0x005 || r.ports[1] != 0x120 || r.ports[2] != 0x121 {
+		t.Errorf("got ports %x", r.ports)
+	}
+	wrongPatches := []Patch{
+		{Net: 128},
+		{SubNet: 16},
+		{Universe: 16},
+		{Offset: 3, Count: 3},
+		{StripIndex: 2},
+	}
+	for _, curPatch := range wrongPatches {
+		_, err := NewReceiver(setup.Config, []Patch{curPatch})
+		if err == nil {
+			t.Errorf("%+v accepted", curPatch)
+		}
+	}
+}
+
+func TestReceiverDmx(t *testing.T) {
+	setup := receivetest.NewSetup(t)
+	r := newTestReceiver(t, setup.Config)
+	sender, receiver := receivetest.Loopback(t)
+	u := receivetest.Unrendered
+	//Rendered once all universes are updated
+	deliver(t, r, sender, receiver, buildDmx(1, 0x120, []byte{1, 2, 3, 0, 0, 0, 0xff, 0xff, 0xff}))
+	deliver(t, r, sender, receiver, buildDmx(1, 0x121, []byte{4, 5, 6}))
+	deliver(t, r, sender, receiver, buildDmx(1, 0x005, []byte{7, 8, 9, 10}))
+	//Sequence 0 is always accepted
+	deliver(t, r, sender, receiver, buildDmx(0, 0x120, []byte{0x11, 0x12, 0x13}))
+	//The repeated universe renders the previous data
+	deliver(t, r, sender, receiver, buildDmx(5, 0x120, []byte{0x21, 0x22, 0x23}))
+	//Old sequence, not patched universes and other packets are ignored
+	deliver(t, r, sender, receiver, buildDmx(4, 0x120, []byte{0xee, 0xee, 0xee}))
+	deliver(t, r, sender, receiver, buildDmx(1, 0x006, []byte{0xee, 0xee, 0xee}))
+	deliver(t, r, sender, receiver, []byte("not Art-Net"))
+	deliver(t, r, sender, receiver, buildDmx(2, 0x121, []byte{0x31, 0x32, 0x33}))
+	deliver(t, r, sender, receiver, buildDmx(2, 0x005, []byte{0x41, 0x42, 0x43, 0x44}))
+	setup.CheckFrames(t, [][][]uint32{
+		//Count limits the first patch
+		{{0x010203, 0, 0, 0x040506, 0}, {0x0a070809, 0, 0}},
+		{{0x111213, 0, 0, 0x040506, 0}, {u, u, u}},
+		{{0x212223, 0, 0, 0x313233, 0}, {0x44414243, 0, 0}},
+	})
+}
+
+func TestReceiverSync(t *testing.T) {
+	setup := receivetest.NewSetup(t)
+	r := newTestReceiver(t, setup.Config)
+	sender, receiver := receivetest.Loopback(t)
+	u := receivetest.Unrendered
+	deliver(t, r, sender, receiver, buildOp(opSync))
+	//Neither complete universes nor repeated universes render
+	deliver(t, r, sender, receiver, buildDmx(1, 0x120, []byte{1, 2, 3}))
+	deliver(t, r, sender, receiver, buildDmx(2, 0x120, []byte{4, 5, 6}))
+	deliver(t, r, sender, receiver, buildDmx(1, 0x121, []byte{7, 8, 9}))
+	deliver(t, r, sender, receiver, buildDmx(1, 0x005, []byte{10, 11, 12, 13}))
+	setup.CheckFrames(t, nil)
+	deliver(t, r, sender, receiver, buildOp(opSync))
+	deliver(t, r, sender, receiver, buildDmx(3, 0x120, []byte{0x11, 0x12, 0x13}))
+	//Without ArtSync the data is rendered without sync again
+	packet, ok := parseDmxPacket(buildDmx(4, 0x120, []byte{0x21, 0x22, 0x23}))
+	if !ok {
+		t.Fatal("invalid packet")
+	}
+	err := r.handleDmx(packet, r.lastSync.Add(syncTimeout+time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	setup.CheckFrames(t, [][][]uint32{
+		{{0x040506, 0, 0, 0x070809, 0}, {0x0d0a0b0c, 0, 0}},
+		{{0x111213, 0, 0, 0x070809, 0}, {u, u, u}},
+	})
+}
+
+func TestReceiverPoll(t *testing.T) {
+	setup := receivetest.NewSetup(t)
+	var patches []Patch
+	for universe := uint8(0); universe < 5; universe++ {
+		patches = append(patches, Patch{Net: 1, SubNet: 2, Universe: universe, StripIndex: 0, Count: 1})
+	}
+	patches = append(patches, Patch{Net: 1, SubNet: 3, Universe: 7, StripIndex: 1})
+	r, err := NewReceiver(setup.Config, patches)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.SetNodeName("test", "test node")
+	r.SetNodeAddress(net.IPv4(10, 1, 2, 3), net.HardwareAddr{1, 2, 3, 4, 5, 6})
+	sender, receiver := receivetest.Loopback(t)
+	deliver(t, r, sender, receiver, buildOp(opPoll))
+	want := []struct {
+		subNet    uint8
+		universes []uint8
+	}{
+		{2, []uint8{0, 1, 2, 3}},
+		{2, []uint8{4}},
+		{3, []uint8{7}},
+	}
+	buf := make([]byte, 1500)
+	for i, curWant := range want {
+		sender.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := sender.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reply := buf[:n]
+		if n != pollReplySize || opCode(reply) != opPollReply {
+			t.Fatalf("reply %d: got %d bytes opcode %x", i, n, opCode(reply))
+		}
+		if !bytes.Equal(reply[10:14], []byte{10, 1, 2, 3}) || !bytes.Equal(reply[201:207], []byte{1, 2, 3, 4, 5, 6}) {
+			t.Errorf("reply %d: got ip %v mac %v", i, reply[10:14], reply[201:207])
+		}
+		if string(bytes.TrimRight(reply[26:43], "\x00")) != "test" {
+			t.Errorf("reply %d: got short name %q", i, reply[26:43])
+		}
+		if reply[18] != 1 || reply[19] != curWant.subNet || reply[211] != uint8(i+1) {
+			t.Errorf("reply %d: got net %d subnet %d bind index %d", i, reply[18], reply[19], reply[211])
+		}
+		portCount := int(binary.BigEndian.Uint16(reply[172:]))
+		if portCount != len(curWant.universes) || !bytes.Equal(reply[190:190+portCount], curWant.universes) {
+			t.Errorf("reply %d: got universes %v", i, reply[190:190+portCount])
+		}
+	}
+}
+
+func TestServe(t *testing.T) {
+	setup := receivetest.NewSetup(t)
+	r := newTestReceiver(t, setup.Config)
+	sender, receiver := receivetest.Loopback(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan error, 1)
+	go func() {
+		served <- r.Serve(ctx, receiver)
+	}()
+	to := receiver.LocalAddr()
+	sender.WriteTo(buildDmx(1, 0x120, []byte{1, 2, 3}), to)
+	sender.WriteTo(buildDmx(1, 0x121, []byte{4, 5, 6}), to)
+	sender.WriteTo(buildDmx(1, 0x005, []byte{7, 8, 9, 10}), to)
+	sender.WriteTo(buildOp(opPoll), to)
+	sender.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err := sender.ReadFrom(make([]byte, 1500))
+	if err != nil {
+		t.Fatalf("no poll reply: %v", err)
+	}
+	cancel()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Serve did not return")
+	}
+	setup.CheckFrames(t, [][][]uint32{
+		{{0x010203, 0, 0, 0x040506, 0}, {0x0a070809, 0, 0}},
+	})
+}
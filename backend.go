@@ -0,0 +1,156 @@
+package rpiws281x
+
+import (
+	"fmt"
+
+	"github.com/DerLukas15/rpigpio"
+	"github.com/DerLukas15/rpihardware"
+	"github.com/DerLukas15/rpimemmap"
+	"github.com/pkg/errors"
+)
+
+//Memory is a mapped memory region. This can be the registers of a peripheral or memory which is accessible by the DMA engine.
+//Offsets are in bytes relative to the start of the region.
+type Memory interface {
+	fmt.Stringer
+	Read32(offset uint32) uint32
+	Write32(offset uint32, val uint32)
+	BusAddr() uint32 // Address of the region as seen by the DMA engine
+	Size() uint32
+	Unmap() error
+}
+
+//SPIDevice is an opened spidev device.
+type SPIDevice interface {
+	Transfer(data []byte) error // Sends data in one transfer and returns once done
+	MaxTransferSize() int       // Largest data accepted by Transfer. 0 if not limited
+	Close() error
+}
+
+//Backend provides all hardware access needed by the drivers.
+/*
+The default backend accesses the real hardware of the Raspberry Pi and requires root. Use SetBackend to replace it,
+for example with a SimulatedBackend to run the package on an ordinary machine.
+*/
+type Backend interface {
+	// Hardware returns the description of the hardware to use.
+	Hardware() (*rpihardware.Hardware, error)
+	// MapPeripheral maps the registers of a peripheral which starts at busOffset relative to the peripheral base.
+	MapPeripheral(busOffset uint32, size uint32) (Memory, error)
+	// MapUncached allocates uncached memory usable by the DMA engine. See rpimemmap for the flags.
+	MapUncached(size uint32, flags uint32) (Memory, error)
+	// InitializeGPIO prepares the GPIO access. Calling it multiple times must be possible.
+	InitializeGPIO() error
+	// PinMode sets the mode of pin.
+	PinMode(pin *rpigpio.Pin, mode rpigpio.Mode) error
+	// PinSet sets the output value of pin.
+	PinSet(pin *rpigpio.Pin, value int) error
+	// OpenSPI opens the spidev device with mode 0, 8 bits per word and speedHz.
+	OpenSPI(device string, speedHz uint32) (SPIDevice, error)
+}
+
+var activeBackend Backend = hardwareBackend{}
+
+//SetBackend replaces the backend used for all hardware access.
+//This is only possible as long as no Config is initialized.
+func SetBackend(backend Backend) error {
+	if pwmActive || pcmActive || spiActive {
+		return errors.Wrap(ErrDriverAlreadyUsed, "SetBackend")
+	}
+	if backend == nil {
+		backend = hardwareBackend{}
+	}
+	//Mappings of the old backend are useless for the new one
+	err := cleanupClock()
+	if err != nil {
+		return errors.Wrap(err, "SetBackend")
+	}
+	err = cleanupDMA()
+	if err != nil {
+		return errors.Wrap(err, "SetBackend")
+	}
+	activeBackend = backend
+	curHardware = nil
+	return nil
+}
+
+//hardwareBackend accesses the real hardware through rpimemmap and rpigpio.
+type hardwareBackend struct{}
+
+//mappedMemory turns a rpimemmap.MemMap into a Memory.
+type mappedMemory struct {
+	rpimemmap.MemMap
+}
+
+func (m mappedMemory) Read32(offset uint32) uint32 {
+	return *rpimemmap.Reg32(m.MemMap, offset)
+}
+
+func (m mappedMemory) Write32(offset uint32, val uint32) {
+	*rpimemmap.Reg32(m.MemMap, offset) = val
+}
+
+//bufferMemory is a Memory in normal Go memory. It can't be used by the DMA engine.
+type bufferMemory []uint32
+
+func (m bufferMemory) String() string {
+	return fmt.Sprintf("Buffer memory\nSize %d bytes\n", m.Size())
+}
+
+func (m bufferMemory) Read32(offset uint32) uint32 {
+	return m[offset/4]
+}
+
+func (m bufferMemory) Write32(offset uint32, val uint32) {
+	m[offset/4] = val
+}
+
+func (m bufferMemory) BusAddr() uint32 {
+	return 0
+}
+
+func (m bufferMemory) Size() uint32 {
+	return uint32(len(m) * 4)
+}
+
+func (m bufferMemory) Unmap() error {
+	return nil
+}
+
+func (hardwareBackend) Hardware() (*rpihardware.Hardware, error) {
+	return rpihardware.Check()
+}
+
+func (hardwareBackend) MapPeripheral(busOffset uint32, size uint32) (Memory, error) {
+	m := rpimemmap.NewPeripheral(size)
+	err := m.Map(busOffset, rpimemmap.MemDevDefault, 0)
+	if err != nil {
+		return nil, err
+	}
+	return mappedMemory{m}, nil
+}
+
+func (hardwareBackend) MapUncached(size uint32, flags uint32) (Memory, error) {
+	m := rpimemmap.NewUncached(size)
+	err := m.Map(0, "", flags)
+	if err != nil {
+		return nil, err
+	}
+	return mappedMemory{m}, nil
+}
+
+func (hardwareBackend) InitializeGPIO() error {
+	return rpigpio.Initialize()
+}
+
+func (hardwareBackend) PinMode(pin *rpigpio.Pin, mode rpigpio.Mode) error {
+	return pin.Mode(mode)
+}
+
+func (hardwareBackend) PinSet(pin *rpigpio.Pin, value int) error {
+	return pin.Set(value)
+}
+
+func (hardwareBackend) OpenSPI(device string, speedHz uint32) (SPIDevice, error) {
+	return openSpidev(device, speedHz)
+}
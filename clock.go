@@ -3,8 +3,6 @@ package rpiws281x
 import (
 	"os"
 	"time"
-
-	"github.com/DerLukas15/rpimemmap"
 )
 
 const (
@@ -34,33 +32,53 @@ var (
 	registerValueClkDivDivf = func(val uint32) uint32 { return ((val & 0xfff) << 0) }  // Fractional part of devisor
 )
 
-var clockRegisterMem rpimemmap.MemMap //stores reference to clock device
+var clockRegisterMem Memory //stores reference to clock device
+
+//Setup the pwm clock with divisor
+func clockSetupPwm(divisor uint32) error {
+	return clockSetup(registerOffsetClkPwmCtl, registerOffsetClkPwmDiv, divisor)
+}
+
+//stops the pwm clock
+func stopClockPWM() error {
+	return stopClock(registerOffsetClkPwmCtl)
+}
 
-//Setup the pwm clock for correct frequency
-func clockSetupPwm(frequency uint32) error {
-	stopClockPWM()
-	*rpimemmap.Reg32(clockRegisterMem, registerOffsetClkPwmDiv) = registerValueClkPasswd | registerValueClkDivDivi(curHardware.OscFreq/(pwmBitsPerOutputBit*frequency))
-	*rpimemmap.Reg32(clockRegisterMem, registerOffsetClkPwmCtl) = registerValueClkPasswd | registerValueClkCtlSrcOsc
-	*rpimemmap.Reg32(clockRegisterMem, registerOffsetClkPwmCtl) = registerValueClkPasswd | registerValueClkCtlSrcOsc | registerValueClkCtlEnab
+//Setup the pcm clock with divisor
+func clockSetupPcm(divisor uint32) error {
+	return clockSetup(registerOffsetClkPcmCtl, registerOffsetClkPcmDiv, divisor)
+}
+
+//stops the pcm clock
+func stopClockPCM() error {
+	return stopClock(registerOffsetClkPcmCtl)
+}
+
+//Setup the clock with control register ctlOffset and divisor register divOffset
+func clockSetup(ctlOffset uint32, divOffset uint32, divisor uint32) error {
+	stopClock(ctlOffset)
+	clockRegisterMem.Write32(divOffset, registerValueClkPasswd|registerValueClkDivDivi(divisor))
+	clockRegisterMem.Write32(ctlOffset, registerValueClkPasswd|registerValueClkCtlSrcOsc)
+	clockRegisterMem.Write32(ctlOffset, registerValueClkPasswd|registerValueClkCtlSrcOsc|registerValueClkCtlEnab)
 	time.Sleep(10 * time.Microsecond)
 	//Wait for clock to setup
 	logOutput("Waiting for clock to start")
-	for (*rpimemmap.Reg32(clockRegisterMem, registerOffsetClkPwmCtl) & registerValueClkCtlBusy) == 0 {
+	for (clockRegisterMem.Read32(ctlOffset) & registerValueClkCtlBusy) == 0 {
 		time.Sleep(1 * time.Microsecond)
 	}
 	logOutput("Done waiting")
 	return nil
 }
 
-//stops the pwm clock
-func stopClockPWM() error {
+//stops the clock with control register ctlOffset
+func stopClock(ctlOffset uint32) error {
 	if clockRegisterMem == nil {
 		return nil
 	}
-	*rpimemmap.Reg32(clockRegisterMem, registerOffsetClkPwmCtl) = registerValueClkPasswd | registerValueClkCtlKill
+	clockRegisterMem.Write32(ctlOffset, registerValueClkPasswd|registerValueClkCtlKill)
 	time.Sleep(10 * time.Microsecond)
 	logOutput("Waiting for clock to stop")
-	for (*rpimemmap.Reg32(clockRegisterMem, registerOffsetClkPwmCtl) & registerValueClkCtlBusy) != 0 {
+	for (clockRegisterMem.Read32(ctlOffset) & registerValueClkCtlBusy) != 0 {
 		time.Sleep(1 * time.Microsecond)
 	}
 	logOutput("Done waiting")
@@ -74,8 +92,8 @@ func initializeClock() error {
 		logOutput("Clock already initialized. Skipping")
 		return nil
 	}
-	clockRegisterMem = rpimemmap.NewPeripheral(uint32(os.Getpagesize()))
-	err := clockRegisterMem.Map(registerClockBusOffset, rpimemmap.MemDevDefault, 0)
+	var err error
+	clockRegisterMem, err = activeBackend.MapPeripheral(registerClockBusOffset, uint32(os.Getpagesize()))
 	if err != nil {
 		return err
 	}
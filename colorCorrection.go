@@ -0,0 +1,120 @@
+package rpiws281x
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+//ColorCorrection holds factors for each color of a strip. 255 leaves the color unchanged, 0 turns it off.
+type ColorCorrection struct {
+	Red   uint8
+	Green uint8
+	Blue  uint8
+	White uint8
+}
+
+//Color correction presets to be used with SetColorCorrection
+var (
+	CorrectionNone               = ColorCorrection{255, 255, 255, 255} // No correction. Default
+	CorrectionTypicalLEDStrip    = ColorCorrection{255, 176, 240, 255} // Typical SMD5050 strips like WS2812B
+	CorrectionTypicalPixelString = ColorCorrection{255, 224, 140, 255} // Typical 8mm pixel strings
+)
+
+//Color temperature presets in Kelvin to be used with SetColorTemperature
+const (
+	TemperatureNone         uint32 = 0    // No white point correction. Default
+	TemperatureCandle       uint32 = 1900 // Candle light
+	TemperatureTungsten     uint32 = 2850 // Incandescent bulb
+	TemperatureHalogen      uint32 = 3200 // Halogen lamp
+	TemperatureDirectSun    uint32 = 5400 // Noon sun
+	TemperatureOvercast     uint32 = 7000 // Overcast sky
+	TemperatureClearBlueSky uint32 = 20000
+)
+
+//SetColorCorrection sets correction factors for the strip with index stripIndex to balance the colors of different LED types and batches.
+//This method can be called once the Config is initialized.
+func (c *Config) SetColorCorrection(correction ColorCorrection, stripIndex int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if stripIndex < 0 || stripIndex >= len(c.channels) {
+		return errors.Wrap(ErrConfigWrongIndex, "config SetColorCorrection")
+	}
+	c.channels[stripIndex].correction = correction
+	return nil
+}
+
+//SetColorTemperature sets the white point of the strip with index stripIndex in Kelvin. Valid values are 0 (disabled) or between 1000 and 40000.
+//The white channel of RGBW strips is not changed.
+//This method can be called once the Config is initialized.
+func (c *Config) SetColorTemperature(kelvin uint32, stripIndex int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if stripIndex < 0 || stripIndex >= len(c.channels) {
+		return errors.Wrap(ErrConfigWrongIndex, "config SetColorTemperature")
+	}
+	if kelvin == TemperatureNone {
+		c.channels[stripIndex].whitePoint = CorrectionNone
+		return nil
+	}
+	if kelvin < 1000 || kelvin > 40000 {
+		return errors.Wrap(ErrWrongTemperature, "config SetColorTemperature")
+	}
+	c.channels[stripIndex].whitePoint = kelvinToCorrection(kelvin)
+	return nil
+}
+
+//approximation of the black body color by Tanner Helland. 6600 K and above is roughly white.
+func kelvinToCorrection(kelvin uint32) ColorCorrection {
+	temp := float64(kelvin) / 100
+	var red, green, blue float64
+	if temp <= 66 {
+		red = 255
+		green = 99.4708025861*math.Log(temp) - 161.1195681661
+	} else {
+		red = 329.698727446 * math.Pow(temp-60, -0.1332047592)
+		green = 288.1221695283 * math.Pow(temp-60, -0.0755148492)
+	}
+	switch {
+	case temp >= 66:
+		blue = 255
+	case temp <= 19:
+		blue = 0
+	default:
+		blue = 138.5177312231*math.Log(temp-10) - 305.0447927307
+	}
+	clamp := func(val float64) uint8 {
+		return uint8(math.Round(math.Max(0, math.Min(255, val))))
+	}
+	return ColorCorrection{
+		Red:   clamp(red),
+		Green: clamp(green),
+		Blue:  clamp(blue),
+		White: 255,
+	}
+}
+
+//returns the factor of the correction for the color at shift in 0xWWRRGGBB
+func (cc ColorCorrection) factor(shift uint8) uint32 {
+	switch (shift / 8) & 0x3 {
+	case 0:
+		return uint32(cc.Blue)
+	case 1:
+		return uint32(cc.Green)
+	case 2:
+		return uint32(cc.Red)
+	}
+	return uint32(cc.White)
+}
+
+//returns the combined scale (1 - 256) of brightness, correction and white point for the color at shift in 0xWWRRGGBB
+func (ch *ledChannel) colorScale(shift uint8) uint32 {
+	scale := uint32((ch.brightness & 0xff)) + 1
+	scale = (scale * (ch.correction.factor(shift) + 1)) >> 8
+	scale = (scale * (ch.whitePoint.factor(shift) + 1)) >> 8
+	if scale == 0 {
+		//value * 0 >> 8 and value * 1 >> 8 are both 0
+		scale = 1
+	}
+	return scale
+}
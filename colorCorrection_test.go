@@ -0,0 +1,98 @@
+package rpiws281x
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestKelvinToCorrection(t *testing.T) {
+	tests := []struct {
+		kelvin uint32
+		want   ColorCorrection
+	}{
+		{1000, ColorCorrection{255, 68, 0, 255}},
+		{TemperatureCandle, ColorCorrection{255, 132, 0, 255}},
+		{6600, ColorCorrection{255, 255, 255, 255}},
+		{40000, ColorCorrection{152, 186, 255, 255}},
+	}
+	for _, test := range tests {
+		if got := kelvinToCorrection(test.kelvin); got != test.want {
+			t.Errorf("%d K: got %v want %v", test.kelvin, got, test.want)
+		}
+	}
+	//Red falls and blue rises with the temperature
+	last := kelvinToCorrection(1000)
+	for kelvin := uint32(1100); kelvin <= 40000; kelvin += 100 {
+		cur := kelvinToCorrection(kelvin)
+		if cur.Red > last.Red || cur.Blue < last.Blue {
+			t.Fatalf("%d K: got %v after %v", kelvin, cur, last)
+		}
+		last = cur
+	}
+}
+
+func TestSetColorTemperature(t *testing.T) {
+	c, _ := New(DriverPWM)
+	err := c.SetStrip(NewLEDStrip(1), 18, WS2812Strip, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		kelvin uint32
+		want   error
+	}{
+		{999, ErrWrongTemperature},
+		{40001, ErrWrongTemperature},
+		{1000, nil},
+		{40000, nil},
+		{TemperatureNone, nil},
+	}
+	for _, test := range tests {
+		err = c.SetColorTemperature(test.kelvin, 0)
+		if errors.Cause(err) != test.want {
+			t.Errorf("%d K: got %v want %v", test.kelvin, err, test.want)
+		}
+	}
+	if c.channels[0].whitePoint != CorrectionNone {
+		t.Errorf("TemperatureNone: got white point %v want %v", c.channels[0].whitePoint, CorrectionNone)
+	}
+	err = c.SetColorTemperature(1000, 2)
+	if errors.Cause(err) != ErrConfigWrongIndex {
+		t.Errorf("SetColorTemperature: got %v want %v", err, ErrConfigWrongIndex)
+	}
+	err = c.SetColorCorrection(CorrectionNone, -1)
+	if errors.Cause(err) != ErrConfigWrongIndex {
+		t.Errorf("SetColorCorrection: got %v want %v", err, ErrConfigWrongIndex)
+	}
+}
+
+func TestColorCorrectionRender(t *testing.T) {
+	strip := NewLEDStrip(2)
+	strip.SetDirect(0, 0xffffffff)
+	strip.SetDirect(1, 0x80808080)
+	c, backend := newPWMTestConfig(t, strip, SK6812StripGRBW)
+	tests := []struct {
+		name  string
+		setup func() error
+		want  []uint32
+	}{
+		{"none", func() error { return nil }, []uint32{0xffffffff, 0x80808080}},
+		{"LED strip", func() error { return c.SetColorCorrection(CorrectionTypicalLEDStrip, 0) }, []uint32{0xffffb0f0, 0x80805878}},
+		{"LED strip at 1000 K", func() error { return c.SetColorTemperature(1000, 0) }, []uint32{0xffff2e00, 0x80801700}},
+		{"1000 K", func() error { return c.SetColorCorrection(CorrectionNone, 0) }, []uint32{0xffff4400, 0x80802200}},
+		{"none again", func() error { return c.SetColorTemperature(TemperatureNone, 0) }, []uint32{0xffffffff, 0x80808080}},
+	}
+	for _, test := range tests {
+		err := test.setup()
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		decoded := renderDecoded(t, c, backend)
+		for i, want := range test.want {
+			if got := decoded[0].UInt32(i); got != want {
+				t.Errorf("%s: LED %d: got %x want %x", test.name, i, got, want)
+			}
+		}
+	}
+}
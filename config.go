@@ -1,11 +1,12 @@
 package rpiws281x
 
 import (
+	"context"
 	"fmt"
-	"time"
+	"strings"
+	"sync"
 
 	"github.com/DerLukas15/rpigpio"
-	"github.com/DerLukas15/rpihardware"
 	"github.com/pkg/errors"
 )
 
@@ -21,25 +22,50 @@ type Config struct {
 	// DMA channel to use. Use different channels if you use multiple drivers simultaniously.
 	// You should be able to use the same channel if you don't run multiple renders at the same time (i.e. `go config.Render()`)
 	dmaChannel uint32
-	//Frequency for communication
-	frequency uint32
+	//Timing of the LEDs and the symbols derived during Initialize
+	timing  TimingProfile
+	symbols *symbolTiming
+	//Device used for SPI
+	spiDevice string
 	//PWM allowes two strips as it has two channels. Thus channels is a slice
 	channels []ledChannel
 
 	initialized bool
 
+	//Serializes Render and Stop. StopAll may run on another goroutine than the render loop
+	mu sync.Mutex
+
+	//Limit of the current of all channels in mA. 0 is unlimited
+	totalPowerLimit float64
+
+	//Records every Render if set
+	recorder *Recorder
+
 	// Timing for next render
-	renderWaitTime     int64
-	previousRenderTime time.Time
+	renderWaitTime int64
+	lastRender     *renderWait
 }
 
 type ledChannel struct {
-	stripType  StripType    // StripType
-	strip      LEDs         // Actual LEDs
-	pin        *rpigpio.Pin // Pin to use for the strip
-	invert     bool         // Set output inverse
-	active     bool         // Set when this channel is configured to be used
-	brightness uint32       // Brightness of the strip
+	stripType    StripType       // StripType
+	strip        LEDs            // Actual LEDs
+	pin          *rpigpio.Pin    // Pin to use for the strip
+	invert       bool            // Set output inverse
+	active       bool            // Set when this channel is configured to be used
+	brightness   uint32          // Brightness of the strip
+	gamma        [4]*GammaCurve  // Gamma per color component. Index is the byte position in 0xWWRRGGBB. nil is linear
+	correction   ColorCorrection // Correction factors of the strip
+	whitePoint   ColorCorrection // Correction factors from the color temperature
+	whiteMode    WhiteMode       // How the white value is set
+	whiteColor   ColorCorrection // Color of the white LEDs
+	encoded      []uint32        // Cached symbols of the last render
+	segments     []*Segment      // Segments with their own brightness
+	segmentScale []uint32        // Cached brightness scale of the segments per LED
+	powerModel   PowerModel      // Current drawn by the LEDs
+	powerLimit   float64         // Limit of the current in mA. 0 is unlimited
+	powerScale   uint32          // Scale (1 - 256) of the output values to stay within the limits
+	idleCurrent  float64         // Estimated current of the LEDs being off in mA
+	fullCurrent  float64         // Estimated current of the last frame without limit in mA
 
 	wshift uint8 //White shift value
 	rshift uint8 //Red shift value
@@ -52,20 +78,31 @@ type ledChannel struct {
 Default Frequency: 800 kHz
 
 Default DMAChannel: 10
+
+Default SPI device: /dev/spidev0.0
 */
 func New(driverType DriverType) (*Config, error) {
 	c := &Config{
 		driverType:  driverType,
 		initialized: false,
 		dmaChannel:  10,
-		frequency:   800000,
+		timing:      TimingWS2812,
+		spiDevice:   "/dev/spidev0.0",
 	}
 	switch c.driverType {
 	case DriverPWM:
 		c.channels = make([]ledChannel, 2, 2)
+	case DriverPCM, DriverSPI:
+		c.channels = make([]ledChannel, 1, 1)
 	default:
 		return nil, errors.Wrap(ErrDriverNotSupported, "New")
 	}
+	for i := range c.channels {
+		c.channels[i].correction = CorrectionNone
+		c.channels[i].whitePoint = CorrectionNone
+		c.channels[i].powerScale = 256
+		c.channels[i].whiteColor = kelvinToCorrection(TemperatureWhiteLED)
+	}
 	return c, nil
 }
 
@@ -84,28 +121,23 @@ func (c *Config) Initialize() error {
 	if !oneActive {
 		return errors.Wrap(ErrNoActiveChannel, "config initialize")
 	}
-	//Initialize GPIO. Does not matter if already done.
-	logOutput("Initializing GPIO package")
-	err := rpigpio.Initialize()
-	if err != nil {
-		return errors.Wrap(err, "config initialize")
-	}
-	logOutput("Done with GPIO package")
-	curHardware, err = rpihardware.Check()
-	if err != nil {
-		return errors.Wrap(err, "config initialize")
+	if c.driverType != DriverSPI {
+		//SPI uses the kernel driver. All others need GPIO and DMA
+		err := c.initializeHardware()
+		if err != nil {
+			return errors.Wrap(err, "config initialize")
+		}
 	}
-	logOutput("Initializing DMA peripheral")
-	err = initializeDMA()
-	if err != nil {
-		return errors.Wrap(err, "config initialize")
+	//SPI is not clocked by this package. Any bit rate is possible
+	var oscFreq uint32
+	if c.driverType != DriverSPI {
+		oscFreq = curHardware.OscFreq
 	}
-	logOutput("Done with DMA peripheral")
-	err = enableDMA(c.dmaChannel)
+	var err error
+	c.symbols, err = newSymbolTiming(c.timing, oscFreq)
 	if err != nil {
 		return errors.Wrap(err, "config initialize")
 	}
-	logOutput("Enabled DMA channel")
 	switch c.driverType {
 	case DriverPWM:
 		if pwmActive {
@@ -114,8 +146,10 @@ func (c *Config) Initialize() error {
 		pwmActive = true
 		//Initialize and start PWM. This will also setup the clock
 		logOutput("Initializing PWM")
-		err := initializePWM(c.channels, c.frequency)
+		err := initializePWM(c.channels, c.symbols)
 		if err != nil {
+			pwmActive = false
+			cleanupPWM()
 			return errors.Wrap(err, "config initialize")
 		}
 		logOutput("Done PWM")
@@ -124,19 +158,57 @@ func (c *Config) Initialize() error {
 			if curChannel.active {
 				//err was checked during SetStrip
 				altMode, _ := pwmChannels.getAltMode(curChannelID, curChannel.pin.UInt32())
-				curChannel.pin.Mode(altMode)
+				activeBackend.PinMode(curChannel.pin, altMode)
 			}
 		}
+	case DriverPCM:
+		if pcmActive {
+			return errors.Wrap(ErrDriverAlreadyUsed, "pcm")
+		}
+		pcmActive = true
+		//Initialize and start PCM. This will also setup the clock
+		logOutput("Initializing PCM")
+		err := initializePCM(c.channels, c.symbols)
+		if err != nil {
+			pcmActive = false
+			cleanupPCM()
+			return errors.Wrap(err, "config initialize")
+		}
+		logOutput("Done PCM")
+		//err was checked during SetStrip
+		altMode, _ := pcmChannels.getAltMode(0, c.channels[0].pin.UInt32())
+		activeBackend.PinMode(c.channels[0].pin, altMode)
+	case DriverSPI:
+		if spiActive {
+			return errors.Wrap(ErrDriverAlreadyUsed, "spi")
+		}
+		spiActive = true
+		logOutput("Initializing SPI")
+		err := initializeSPI(c.channels, c.symbols, c.spiDevice)
+		if err != nil {
+			spiActive = false
+			cleanupSPI()
+			return errors.Wrap(err, "config initialize")
+		}
+		logOutput("Done SPI")
 	default:
 		return errors.Wrap(ErrDriverNotSupported, "config initialize")
 	}
 	c.initialized = true
+	registerConfig(c)
 	return nil
 }
 
 //Stop will dsable the Config so that another Config of same driverType can be initialized.
 //Stopping is also needed if changing of fundamental settings is desired.
 func (c *Config) Stop() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stop()
+}
+
+//stops the Config. c.mu has to be held
+func (c *Config) stop() error {
 	if !c.initialized {
 		return nil
 	}
@@ -149,16 +221,26 @@ func (c *Config) Stop() error {
 		}
 	case DriverPCM:
 		pcmActive = false
+		err := cleanupPCM()
+		if err != nil {
+			return errors.Wrap(err, "config Stop")
+		}
 	case DriverSPI:
 		spiActive = false
+		err := cleanupSPI()
+		if err != nil {
+			return errors.Wrap(err, "config Stop")
+		}
 	}
 	//Don't stop DMA as other config might use it.
 	c.initialized = false
+	unregisterConfig(c)
 	for _, curChan := range c.channels {
-		if curChan.active {
-			fmt.Println("Setting pinmode")
-			curChan.pin.Mode(rpigpio.ModeOut)
-			curChan.pin.Set(0)
+		//Pins of SPI are handled by the kernel driver
+		if curChan.active && c.driverType != DriverSPI {
+			logOutput("Setting pinmode")
+			activeBackend.PinMode(curChan.pin, rpigpio.ModeOut)
+			activeBackend.PinSet(curChan.pin, 0)
 		}
 	}
 	return nil
@@ -178,7 +260,22 @@ func (c *Config) SetDMAChannel(channel uint32) error {
 	return nil
 }
 
+//SetSPIDevice sets the spidev device to use for SPI. Default is /dev/spidev0.0 which uses GPIO 10.
+//The device has to be enabled (i.e. dtparam=spi=on). Root is not needed if the user has access to the device.
+//Only the devices of SPI0 (/dev/spidev0.x) are supported as the strip has to be on GPIO 10.
+func (c *Config) SetSPIDevice(device string) error {
+	if c.initialized {
+		return errors.Wrap(ErrConfigInitialized, "config SetSPIDevice")
+	}
+	if !strings.HasPrefix(device, spiDevicePrefix) || len(device) == len(spiDevicePrefix) {
+		return errors.Wrap(ErrWrongSPIDevice, "config SetSPIDevice")
+	}
+	c.spiDevice = device
+	return nil
+}
+
 //SetFrequency sets the output frequency to use. Valid values are 400000 and 800000
+//The high times are a third and two thirds of a bit. Use SetTiming for other chips.
 func (c *Config) SetFrequency(frequency uint32) error {
 	if c.initialized {
 		return errors.Wrap(ErrConfigInitialized, "config SetFrequency")
@@ -186,7 +283,7 @@ func (c *Config) SetFrequency(frequency uint32) error {
 	if frequency != 400000 && frequency != 800000 {
 		return errors.Wrap(ErrWrongFrequency, "config SetFrequency")
 	}
-	c.frequency = frequency
+	c.timing = frequencyTiming(frequency)
 	return nil
 }
 
@@ -203,6 +300,12 @@ func (c *Config) SetBrightness(brightness uint32, stripIndex int) error {
 		}
 		c.channels[stripIndex].brightness = brightness
 		logOutput(fmt.Sprintf("Setting brightness of strip: %d\n", c.channels[stripIndex].brightness))
+	case DriverPCM, DriverSPI:
+		if stripIndex >= 1 {
+			return errors.Wrap(ErrConfigWrongIndex, "config SetBrightness")
+		}
+		c.channels[stripIndex].brightness = brightness
+		logOutput(fmt.Sprintf("Setting brightness of strip: %d\n", c.channels[stripIndex].brightness))
 	}
 	return nil
 }
@@ -241,53 +344,163 @@ func (c *Config) SetStrip(ledStrip LEDs, pin uint32, stripType StripType, stripI
 		if err != nil {
 			return err
 		}
-		curChannel.pin, err = rpigpio.NewPin(pin)
+		err = curChannel.setStrip(ledStrip, pin, stripType, invertSignal)
+		if err != nil {
+			return err
+		}
+	case DriverPCM:
+		if stripIndex >= 1 {
+			return errors.Wrap(ErrConfigWrongIndex, "config SetStrip")
+		}
+		curChannel := &c.channels[stripIndex]
+		//Checking if pin is allowed for PCM
+		_, err := pcmChannels.getAltMode(stripIndex, pin)
+		if err != nil {
+			return err
+		}
+		err = curChannel.setStrip(ledStrip, pin, stripType, invertSignal)
+		if err != nil {
+			return err
+		}
+	case DriverSPI:
+		if stripIndex >= 1 {
+			return errors.Wrap(ErrConfigWrongIndex, "config SetStrip")
+		}
+		curChannel := &c.channels[stripIndex]
+		//Checking if pin is allowed for SPI
+		_, err := spiChannels.getAltMode(stripIndex, pin)
+		if err != nil {
+			return err
+		}
+		err = curChannel.setStrip(ledStrip, pin, stripType, invertSignal)
 		if err != nil {
 			return err
 		}
-		curChannel.strip = ledStrip
-		curChannel.stripType = stripType
-		curChannel.invert = invertSignal
-		curChannel.active = true
-		curChannel.wshift = uint8((stripType >> 24) & 0xff)
-		curChannel.rshift = uint8((stripType >> 16) & 0xff)
-		curChannel.gshift = uint8((stripType >> 8) & 0xff)
-		curChannel.bshift = uint8((stripType >> 0) & 0xff)
 	}
 	return nil
 }
 
 //stripIndex -1 renders all stripes for that driver
+//ErrConfigNotInitialized is returned if the Config is not initialized or was stopped meanwhile, i.e. by StopAll.
 func (c *Config) Render(stripIndex int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.render(stripIndex)
+}
+
+//renders like Render. c.mu has to be held
+func (c *Config) render(stripIndex int) error {
+	if !c.initialized {
+		return errors.Wrap(ErrConfigNotInitialized, "config Render")
+	}
 	if stripIndex < -1 {
 		return errors.Wrap(ErrConfigWrongIndex, "")
 	}
+	c.limitPower()
 	switch c.driverType {
 	case DriverPWM:
 		if stripIndex >= 2 {
 			return errors.Wrap(ErrConfigWrongIndex, "")
 		}
-		if c.renderWaitTime != 0 && !c.previousRenderTime.IsZero() {
-			timeDiff := time.Now().Sub(c.previousRenderTime)
-			if timeDiff.Microseconds() < c.renderWaitTime {
-				time.Sleep(time.Duration((c.renderWaitTime - timeDiff.Microseconds()) * 1000))
-			}
+		//Encode into the idle buffer while the previous frame might still be sent
+		waitTime, err := renderPWM(c.channels, stripIndex, c.symbols)
+		if err != nil {
+			return err
+		}
+		err = c.wait(context.Background())
+		if err != nil {
+			return err
+		}
+		err = startPWM(c.dmaChannel)
+		if err != nil {
+			return err
+		}
+		c.renderWaitTime = waitTime
+		c.renderStarted()
+	case DriverPCM:
+		if stripIndex >= 1 {
+			return errors.Wrap(ErrConfigWrongIndex, "")
+		}
+		err := c.wait(context.Background())
+		if err != nil {
+			return err
+		}
+		c.renderWaitTime, err = renderPCM(c.channels, c.symbols)
+		if err != nil {
+			return err
+		}
+		err = startDMA(c.dmaChannel, dmaCBRegisterMemPCM.BusAddr())
+		if err != nil {
+			return err
 		}
-		var err error
-		if stripIndex == -1 {
-			c.renderWaitTime, err = renderPWM(c.channels, c.frequency)
-		} else {
-			c.renderWaitTime, err = renderPWM(c.channels[stripIndex:stripIndex+1], c.frequency)
+		c.renderStarted()
+	case DriverSPI:
+		if stripIndex >= 1 {
+			return errors.Wrap(ErrConfigWrongIndex, "")
 		}
+		err := c.wait(context.Background())
 		if err != nil {
 			return err
 		}
-		err = startDMA(c.dmaChannel, dmaCBRegisterMemPWM.BusAddr())
+		//The transfer is done once renderSPI returns
+		c.renderWaitTime, err = renderSPI(c.channels, c.symbols)
 		if err != nil {
 			return err
 		}
-		c.previousRenderTime = time.Now()
+		c.renderStarted()
+	}
+
+	if c.recorder != nil {
+		err := c.recorder.record(c.channels, stripIndex)
+		if err != nil {
+			return errors.Wrap(err, "config Render")
+		}
 	}
+	return nil
+}
+
+//sets the strip of the channel. The pin has to be checked for the driver beforehand.
+func (ch *ledChannel) setStrip(ledStrip LEDs, pin uint32, stripType StripType, invertSignal bool) error {
+	var err error
+	ch.pin, err = rpigpio.NewPin(pin)
+	if err != nil {
+		return err
+	}
+	ch.strip = ledStrip
+	ch.segments = nil
+	ch.stripType = stripType
+	ch.invert = invertSignal
+	ch.active = true
+	ch.wshift = uint8((stripType >> 24) & 0xff)
+	ch.rshift = uint8((stripType >> 16) & 0xff)
+	ch.gshift = uint8((stripType >> 8) & 0xff)
+	ch.bshift = uint8((stripType >> 0) & 0xff)
+	return nil
+}
 
+//initializes GPIO, hardware and DMA needed by PWM and PCM
+func (c *Config) initializeHardware() error {
+	//Initialize GPIO. Does not matter if already done.
+	logOutput("Initializing GPIO package")
+	err := activeBackend.InitializeGPIO()
+	if err != nil {
+		return err
+	}
+	logOutput("Done with GPIO package")
+	curHardware, err = activeBackend.Hardware()
+	if err != nil {
+		return err
+	}
+	logOutput("Initializing DMA peripheral")
+	err = initializeDMA()
+	if err != nil {
+		return err
+	}
+	logOutput("Done with DMA peripheral")
+	err = enableDMA(c.dmaChannel)
+	if err != nil {
+		return err
+	}
+	logOutput("Enabled DMA channel")
 	return nil
 }
@@ -0,0 +1,155 @@
+package rpiws281x
+
+import (
+	"testing"
+
+	"github.com/DerLukas15/rpigpio"
+	"github.com/pkg/errors"
+)
+
+//returns a strip with a different color on every LED
+func testStrip(count int, white bool) *LEDStrip {
+	res := NewLEDStrip(count)
+	for i := 0; i < count; i++ {
+		val := (uint32(0x123456) + uint32(i)*0x0b0d11) & 0xffffff
+		if white {
+			val |= uint32(0x20+i) << 24
+		}
+		res.SetDirect(i, val)
+	}
+	return res
+}
+
+//checks that decoded contains the colors of strips
+func checkDecoded(t *testing.T, decoded []*DecodedStrip, strips ...LEDs) {
+	t.Helper()
+	if len(decoded) != len(strips) {
+		t.Fatalf("got %d channels want %d", len(decoded), len(strips))
+	}
+	for chanID, curStrip := range strips {
+		for i := 0; i < curStrip.TotalCount(); i++ {
+			if decoded[chanID].UInt32(i) != curStrip.UInt32(i) {
+				t.Errorf("channel %d LED %d: got %x want %x", chanID, i, decoded[chanID].UInt32(i), curStrip.UInt32(i))
+			}
+		}
+	}
+}
+
+//checks the mode and level of pin
+func checkPin(t *testing.T, backend *SimulatedBackend, pin uint32, mode rpigpio.Mode, level int) {
+	t.Helper()
+	gotMode, gotLevel := backend.PinState(pin)
+	if gotMode != mode || gotLevel != level {
+		t.Errorf("pin %d: got mode %v level %d want mode %v level %d", pin, gotMode, gotLevel, mode, level)
+	}
+}
+
+//checks a register against want. Only the bits in mask are compared
+func checkRegister(t *testing.T, name string, mem Memory, offset uint32, mask uint32, want uint32) {
+	t.Helper()
+	if got := mem.Read32(offset) & mask; got != want {
+		t.Errorf("%s: got %#x want %#x", name, got, want)
+	}
+}
+
+func TestRenderNotInitialized(t *testing.T) {
+	c, _ := New(DriverPWM)
+	err := c.SetStrip(NewLEDStrip(1), 18, WS2812Strip, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Render(-1)
+	if errors.Cause(err) != ErrConfigNotInitialized {
+		t.Errorf("got %v want %v", err, ErrConfigNotInitialized)
+	}
+	err = c.Stop()
+	if err != nil {
+		t.Errorf("Stop of a not initialized config: %v", err)
+	}
+}
+
+var errTestMapUncached = errors.New("no uncached memory")
+
+//noUncachedBackend fails to allocate uncached memory after the peripherals are mapped
+type noUncachedBackend struct {
+	*SimulatedBackend
+}
+
+func (noUncachedBackend) MapUncached(size uint32, flags uint32) (Memory, error) {
+	return nil, errTestMapUncached
+}
+
+func TestInitializeFailure(t *testing.T) {
+	tests := []struct {
+		driverType DriverType
+		pin        uint32
+	}{
+		{DriverPWM, 18},
+		{DriverPCM, 21},
+	}
+	for _, test := range tests {
+		err := SetBackend(noUncachedBackend{NewSimulatedBackend()})
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, _ := New(test.driverType)
+		c.SetStrip(NewLEDStrip(10), test.pin, WS2812Strip, 0, false)
+		err = c.Initialize()
+		if errors.Cause(err) != errTestMapUncached {
+			c.Stop()
+			t.Fatalf("driver %d: got %v want %v", test.driverType, err, errTestMapUncached)
+		}
+		//A failed Initialize must not block the driver
+		err = SetBackend(NewSimulatedBackend())
+		if err != nil {
+			t.Fatalf("driver %d: %v", test.driverType, err)
+		}
+		c, _ = New(test.driverType)
+		c.SetStrip(NewLEDStrip(10), test.pin, WS2812Strip, 0, false)
+		err = c.Initialize()
+		if err != nil {
+			t.Fatalf("driver %d: %v", test.driverType, err)
+		}
+		err = c.Stop()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+//returns an initialized PWM config with strip on pin 18 at full brightness on the simulated backend.
+//The config is stopped at the end of the test
+func newPWMTestConfig(t *testing.T, strip LEDs, stripType StripType) (*Config, *SimulatedBackend) {
+	t.Helper()
+	backend := NewSimulatedBackend()
+	err := SetBackend(backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := New(DriverPWM)
+	err = c.SetStrip(strip, 18, stripType, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetBrightness(255, 0)
+	err = c.Initialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Stop() })
+	return c, backend
+}
+
+//renders all strips of c and returns the decoded channels
+func renderDecoded(t *testing.T, c *Config, backend *SimulatedBackend) []*DecodedStrip {
+	t.Helper()
+	err := c.Render(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodePWM(backend.LastTransfer(c.dmaChannel), c.PWMLayout())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
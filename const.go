@@ -10,15 +10,39 @@ import (
 
 // Errors
 var (
-	ErrNoClockMap         = errors.New("clock device map not set. Not initialized?")
-	ErrNoHardware         = errors.New("no hardware set. Not initialized?")
-	ErrDriverAlreadyUsed  = errors.New("driver already initialized")
-	ErrConfigInitialized  = errors.New("config already initialized")
-	ErrConfigWrongIndex   = errors.New("wrong strip index")
-	ErrDriverNotSupported = errors.New("driver not supported")
-	ErrPinNotAllowed      = errors.New("selected pin not allowed")
-	ErrNoActiveChannel    = errors.New("No active channel")
-	ErrWrongFrequency     = errors.New("Wrong Frequency")
+	ErrNoClockMap           = errors.New("clock device map not set. Not initialized?")
+	ErrNoHardware           = errors.New("no hardware set. Not initialized?")
+	ErrDriverAlreadyUsed    = errors.New("driver already initialized")
+	ErrConfigInitialized    = errors.New("config already initialized")
+	ErrConfigWrongIndex     = errors.New("wrong strip index")
+	ErrDriverNotSupported   = errors.New("driver not supported")
+	ErrPinNotAllowed        = errors.New("selected pin not allowed")
+	ErrNoActiveChannel      = errors.New("No active channel")
+	ErrWrongFrequency       = errors.New("Wrong Frequency")
+	ErrDecodeSymbol         = errors.New("invalid symbol in data")
+	ErrDecodeTooShort       = errors.New("data too short for LED count")
+	ErrDecodeNoReset        = errors.New("data after last LED")
+	ErrWrongGamma           = errors.New("gamma has to be greater than 0")
+	ErrWrongColorComponent  = errors.New("wrong color component")
+	ErrWrongTemperature     = errors.New("color temperature out of range")
+	ErrRenderTimeout        = errors.New("render did not finish in time")
+	ErrWrongFPS             = errors.New("fps has to be greater than 0")
+	ErrWrongMatrixSize      = errors.New("matrix size invalid or larger than the LEDs")
+	ErrWrongMatrixOrder     = errors.New("wrong matrix order")
+	ErrWrongMatrixRotation  = errors.New("wrong matrix rotation")
+	ErrWrongStripRange      = errors.New("range outside of the strip")
+	ErrWrongSegmentParent   = errors.New("segment is not part of the strip")
+	ErrWrongPowerLimit      = errors.New("power limit has to be 0 or greater")
+	ErrWrongWhiteMode       = errors.New("wrong white mode")
+	ErrWrongTiming          = errors.New("wrong timing profile")
+	ErrRecordFormat         = errors.New("invalid recording")
+	ErrRecordVersion        = errors.New("unsupported recording version")
+	ErrWrongSpeed           = errors.New("speed has to be greater than 0")
+	ErrConfigNotInitialized = errors.New("config not initialized")
+	ErrSPIFrameTooLarge     = errors.New("frame larger than the SPI transfer size")
+	ErrWrongSPIDevice       = errors.New("only the devices of SPI0 are supported")
+	ErrRecordChannelCount   = errors.New("too many channels for a recording")
+	ErrRecordStripsChanged  = errors.New("strips differ from the recording")
 )
 
 var (
@@ -69,28 +93,12 @@ const (
 	SK6812WStrip = SK6812StripGRBW
 )
 
-const (
-	symbolHigh uint8 = 0b110
-	symbolLow  uint8 = 0b100
-
-	symbolHighInv = ^symbolHigh
-	symbolLowInv  = ^symbolLow
-)
-
-var gammaTable = make([]uint8, 256, 256)
-
 //Enable Debug output
 var Debug bool
 
 //Enable two channel mode for PWM no matter the configuration
 var PWMAlwaysUseTwoChannel bool
 
-func init() {
-	for x := 0; x < 256; x++ {
-		gammaTable[x] = uint8(x)
-	}
-}
-
 func logOutput(msg string) {
 	if Debug {
 		fmt.Println(msg)
@@ -0,0 +1,107 @@
+//Package ddp receives Distributed Display Protocol (DDP) packets and writes the pixels to the strips of a rpiws281x.Config.
+package ddp
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// Errors
+var (
+	ErrNotWritable = errors.New("strip is not writable")
+	ErrNoStrips    = errors.New("no strips")
+)
+
+const (
+	Port = 4048 // UDP port of DDP
+
+	headerSize         = 10
+	timecodeSize       = 4
+	flagVersionMask    = 0xc0
+	flagVersion1       = 0x40
+	flagTimecode       = 0x10
+	flagStorage        = 0x08
+	flagReply          = 0x04
+	flagQuery          = 0x02
+	flagPush           = 0x01
+	typeRGBW           = 0x1b // 8 bit RGBW pixels. Other types are handled as 8 bit RGB
+	idDefault          = 1    // Default output device
+	idConfig           = 250
+	idStatus           = 251
+	idAll              = 255
+	maxPacketSize      = 1500
+	bytesPerRGBPixel   = 3
+	bytesPerRGBWPixel  = 4
+	deviceManufacturer = "rpiws281x"
+)
+
+//DDP packet
+type packet struct {
+	flags    uint8
+	dataType uint8
+	id       uint8
+	offset   int // Byte offset of data
+	data     []byte
+}
+
+//parses a packet. ok is false if the packet is not a valid version 1 packet
+func parsePacket(buf []byte) (res packet, ok bool) {
+	if len(buf) < headerSize || buf[0]&flagVersionMask != flagVersion1 {
+		return res, false
+	}
+	res.flags = buf[0]
+	res.dataType = buf[2]
+	res.id = buf[3]
+	res.offset = int(binary.BigEndian.Uint32(buf[4:]))
+	length := int(binary.BigEndian.Uint16(buf[8:]))
+	start := headerSize
+	if res.flags&flagTimecode != 0 {
+		start += timecodeSize
+	}
+	if start+length > len(buf) {
+		return res, false
+	}
+	res.data = buf[start : start+length]
+	return res, true
+}
+
+//returns the bytes per pixel of the data type
+func bytesPerPixel(dataType uint8) int {
+	if dataType == typeRGBW {
+		return bytesPerRGBWPixel
+	}
+	return bytesPerRGBPixel
+}
+
+//builds a reply to a query of id with data
+func reply(id uint8, data []byte) []byte {
+	res := make([]byte, headerSize, headerSize+len(data))
+	res[0] = flagVersion1 | flagReply | flagPush
+	res[3] = id
+	binary.BigEndian.PutUint16(res[8:], uint16(len(data)))
+	return append(res, data...)
+}
+
+//status reply
+type statusReply struct {
+	Status struct {
+		Manufacturer string `json:"man"`
+		Model        string `json:"mod"`
+		Push         bool   `json:"push"`
+	} `json:"status"`
+}
+
+//config reply
+type configReply struct {
+	Config struct {
+		IP    string       `json:"ip,omitempty"`
+		Ports []portConfig `json:"ports"`
+	} `json:"config"`
+}
+
+//a strip in the config reply
+type portConfig struct {
+	Port       int `json:"port"` // Strip index
+	Length     int `json:"l"`    // Number of LEDs
+	StartPixel int `json:"ss"`   // First pixel in the offset space
+}
@@ -0,0 +1,212 @@
+package ddp
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+
+	"github.com/DerLukas15/rpiws281x"
+	"github.com/DerLukas15/rpiws281x/internal/receive"
+	"github.com/pkg/errors"
+)
+
+//a strip in the offset space
+type strip struct {
+	stripIndex int
+	leds       rpiws281x.WritableLEDs
+	start      int // First pixel
+}
+
+//Receiver writes the pixels of DDP packets to the strips of a Config and renders them.
+/*
+The strips are placed one after the other in the pixel space. The byte offset of a packet is divided by the bytes per pixel
+of its data type: 4 for RGBW and 3 for every other type. The white value of RGBW strips is set to 0 for RGB data.
+Pixels which are split between two packets are skipped.
+
+Packets with the PUSH flag render all strips which were written since the last PUSH. Status and config queries are answered
+with JSON.
+
+A Receiver is not safe for concurrent use. Render is called from the goroutine handling the packets.
+*/
+type Receiver struct {
+	config  *rpiws281x.Config
+	strips  []strip
+	written []bool // Strips written since the last push
+}
+
+//NewReceiver returns a Receiver writing to the strips of config with the indexes stripIndexes in this order. If stripIndexes
+//is empty, all strips set with SetStrip before are used in the order of their index.
+func NewReceiver(config *rpiws281x.Config, stripIndexes []int) (*Receiver, error) {
+	if len(stripIndexes) == 0 {
+		for stripIndex := 0; ; stripIndex++ {
+			_, _, err := config.Strip(stripIndex)
+			if errors.Cause(err) == rpiws281x.ErrConfigWrongIndex {
+				break
+			}
+			if err == nil {
+				stripIndexes = append(stripIndexes, stripIndex)
+			}
+		}
+	}
+	if len(stripIndexes) == 0 {
+		return nil, errors.Wrap(ErrNoStrips, "new receiver")
+	}
+	r := &Receiver{
+		config:  config,
+		written: make([]bool, len(stripIndexes)),
+	}
+	start := 0
+	for _, stripIndex := range stripIndexes {
+		leds, _, err := config.Strip(stripIndex)
+		if err != nil {
+			return nil, errors.Wrap(err, "new receiver")
+		}
+		writable, ok := leds.(rpiws281x.WritableLEDs)
+		if !ok {
+			return nil, errors.Wrap(ErrNotWritable, "new receiver")
+		}
+		r.strips = append(r.strips, strip{
+			stripIndex: stripIndex,
+			leds:       writable,
+			start:      start,
+		})
+		start += writable.TotalCount()
+	}
+	return r, nil
+}
+
+//Handle processes one DDP packet received from addr. Queries are answered on conn. Other packets than DDP version 1 and
+//packets for other destinations are ignored. An error is returned if Render or the reply fails.
+func (r *Receiver) Handle(conn net.PacketConn, addr net.Addr, buf []byte) error {
+	p, ok := parsePacket(buf)
+	if !ok || p.flags&flagReply != 0 {
+		return nil
+	}
+	if p.flags&flagQuery != 0 {
+		return r.answerQuery(conn, addr, p.id)
+	}
+	if p.id != idDefault && p.id != idAll {
+		return nil
+	}
+	if p.flags&flagStorage == 0 {
+		r.write(p)
+	}
+	if p.flags&flagPush == 0 {
+		return nil
+	}
+	return r.render()
+}
+
+//writes the pixels of p to the strips
+func (r *Receiver) write(p packet) {
+	pixelSize := bytesPerPixel(p.dataType)
+	data := p.data
+	pixel := p.offset / pixelSize
+	if skip := p.offset % pixelSize; skip != 0 {
+		//The rest of a pixel of the previous packet
+		if len(data) < pixelSize-skip {
+			return
+		}
+		data = data[pixelSize-skip:]
+		pixel++
+	}
+	end := pixel + len(data)/pixelSize
+	for i, curStrip := range r.strips {
+		stripEnd := curStrip.start + curStrip.leds.TotalCount()
+		if pixel >= stripEnd || end <= curStrip.start {
+			continue
+		}
+		first := pixel
+		if first < curStrip.start {
+			first = curStrip.start
+		}
+		rpiws281x.SetSlots(curStrip.leds, first-curStrip.start, data[(first-pixel)*pixelSize:], pixelSize)
+		r.written[i] = true
+	}
+}
+
+//renders the strips written since the last push
+func (r *Receiver) render() error {
+	var set receive.RenderSet
+	for i, curStrip := range r.strips {
+		if !r.written[i] {
+			continue
+		}
+		r.written[i] = false
+		set.Add(curStrip.stripIndex)
+	}
+	err := set.Render(r.config)
+	if err != nil {
+		return errors.Wrap(err, "receiver render")
+	}
+	return nil
+}
+
+//sends the status or config JSON to addr
+func (r *Receiver) answerQuery(conn net.PacketConn, addr net.Addr, id uint8) error {
+	var res interface{}
+	switch id {
+	case idStatus:
+		var status statusReply
+		status.Status.Manufacturer = deviceManufacturer
+		status.Status.Model = deviceManufacturer
+		status.Status.Push = true
+		res = status
+	case idConfig:
+		var config configReply
+		if localAddr, ok := conn.LocalAddr().(*net.UDPAddr); ok && !localAddr.IP.IsUnspecified() {
+			config.Config.IP = localAddr.IP.String()
+		}
+		for _, curStrip := range r.strips {
+			config.Config.Ports = append(config.Config.Ports, portConfig{
+				Port:       curStrip.stripIndex,
+				Length:     curStrip.leds.TotalCount(),
+				StartPixel: curStrip.start,
+			})
+		}
+		res = config
+	default:
+		return nil
+	}
+	data, err := json.Marshal(res)
+	if err != nil {
+		return errors.Wrap(err, "receiver query")
+	}
+	_, err = conn.WriteTo(reply(id, data), addr)
+	if err != nil {
+		return errors.Wrap(err, "receiver query")
+	}
+	return nil
+}
+
+//Serve handles the packets received on conn until ctx is done or an error occurs. conn is not closed.
+//A done ctx is not an error.
+func (r *Receiver) Serve(ctx context.Context, conn net.PacketConn) error {
+	stop := receive.UnblockReads(ctx, conn)
+	defer stop()
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "receiver serve")
+		}
+		err = r.Handle(conn, addr, buf[:n])
+		if err != nil {
+			return errors.Wrap(err, "receiver serve")
+		}
+	}
+}
+
+//ListenAndServe listens on the DDP port and handles the packets until ctx is done or an error occurs.
+//A done ctx is not an error.
+func (r *Receiver) ListenAndServe(ctx context.Context) error {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{Port: Port})
+	if err != nil {
+		return errors.Wrap(err, "receiver listen")
+	}
+	defer conn.Close()
+	return r.Serve(ctx, conn)
+}
@@ -0,0 +1,187 @@
+package ddp
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/DerLukas15/rpiws281x/internal/receivetest"
+)
+
+//builds a DDP packet. The timecode is added if flagTimecode is set
+func buildPacket(flags, dataType, id uint8, offset int, data []byte) []byte {
+	res := make([]byte, headerSize)
+	res[0] = flags
+	res[2] = dataType
+	res[3] = id
+	binary.BigEndian.PutUint32(res[4:], uint32(offset))
+	binary.BigEndian.PutUint16(res[8:], uint16(len(data)))
+	if flags&flagTimecode != 0 {
+		res = append(res, make([]byte, timecodeSize)...)
+	}
+	return append(res, data...)
+}
+
+//returns a sender and a receiver on the loopback interface
+func loopback(t *testing.T) (sender net.PacketConn, receiver net.PacketConn) {
+	t.Helper()
+	var conns [2]net.PacketConn
+	for i := range conns {
+		conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		conns[i] = conn
+	}
+	return conns[0], conns[1]
+}
+
+//sends packet over the loopback interface and handles it with r
+func deliver(t *testing.T, r *Receiver, sender net.PacketConn, receiver net.PacketConn, packet []byte) {
+	t.Helper()
+	data, addr := receivetest.Transfer(t, sender, receiver, packet)
+	err := r.Handle(receiver, addr, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewReceiver(t *testing.T) {
+	setup := receivetest.NewSetup(t)
+	r, err := NewReceiver(setup.Config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.strips) != 2 || r.strips[0].start != 0 || r.strips[1].stripIndex != 1 || r.strips[1].start != 5 {
+		t.Errorf("got strips %+v", r.strips)
+	}
+	r, err = NewReceiver(setup.Config, []int{1, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.strips[0].stripIndex != 1 || r.strips[1].start != 3 {
+		t.Errorf("got strips %+v", r.strips)
+	}
+	_, err = NewReceiver(setup.Config, []int{2})
+	if err == nil {
+		t.Error("strip 2 accepted")
+	}
+}
+
+func TestReceiverPush(t *testing.T) {
+	setup := receivetest.NewSetup(t)
+	r, err := NewReceiver(setup.Config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, receiver := receivetest.Loopback(t)
+	u := receivetest.Unrendered
+	const rgb = 0x0b // 8 bit RGB
+	//Pixels of both strips
+	deliver(t, r, sender, receiver, buildPacket(flagVersion1, rgb, idDefault, 3*3, []byte{1, 2, 3, 4, 5, 6}))
+	deliver(t, r, sender, receiver, buildPacket(flagVersion1|flagPush, rgb, idDefault, 5*3, []byte{7, 8, 9, 10, 11, 12}))
+	//RGBW pixels use 4 bytes
+	deliver(t, r, sender, receiver, buildPacket(flagVersion1|flagPush, typeRGBW, idDefault, 6*4, []byte{1, 2, 3, 4}))
+	//The rest of pixel 4 is skipped
+	deliver(t, r, sender, receiver, buildPacket(flagVersion1|flagPush, rgb, idAll, 4*3+1, []byte{0xff, 0xff, 0x21, 0x22, 0x23}))
+	//Ignored packets
+	deliver(t, r, sender, receiver, buildPacket(flagVersion1|flagPush|flagStorage, rgb, idDefault, 0, []byte{0xee, 0xee, 0xee}))
+	deliver(t, r, sender, receiver, buildPacket(flagVersion1|flagPush, rgb, 2, 0, []byte{0xee, 0xee, 0xee}))
+	deliver(t, r, sender, receiver, buildPacket(flagVersion1|flagPush|flagReply, rgb, idDefault, 0, []byte{0xee, 0xee, 0xee}))
+	deliver(t, r, sender, receiver, buildPacket(0x80|flagPush, rgb, idDefault, 0, []byte{0xee, 0xee, 0xee}))
+	deliver(t, r, sender, receiver, buildPacket(flagVersion1|flagPush|flagTimecode, rgb, idDefault, 0, []byte{0x31, 0x32, 0x33}))
+	setup.CheckFrames(t, [][][]uint32{
+		{{0, 0, 0, 0x010203, 0x040506}, {0x070809, 0x0a0b0c, 0}},
+		{{u, u, u, u, u}, {0x070809, 0x04010203, 0}},
+		{{u, u, u, u, u}, {0x212223, 0x04010203, 0}},
+		{{0x313233, 0, 0, 0x010203, 0x040506}, {u, u, u}},
+	})
+}
+
+func TestReceiverQuery(t *testing.T) {
+	setup := receivetest.NewSetup(t)
+	r, err := NewReceiver(setup.Config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, receiver := receivetest.Loopback(t)
+	buf := make([]byte, 1500)
+	readReply := func(id uint8, v interface{}) {
+		t.Helper()
+		deliver(t, r, sender, receiver, buildPacket(flagVersion1|flagQuery, 0, id, 0, nil))
+		sender.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := sender.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reply, ok := parsePacket(buf[:n])
+		if !ok || reply.flags&flagReply == 0 || reply.id != id {
+			t.Fatalf("invalid reply % x", buf[:n])
+		}
+		err = json.Unmarshal(reply.data, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	var status statusReply
+	readReply(idStatus, &status)
+	if status.Status.Manufacturer != deviceManufacturer || !status.Status.Push {
+		t.Errorf("got status %+v", status)
+	}
+	var config configReply
+	readReply(idConfig, &config)
+	if config.Config.IP != "127.0.0.1" {
+		t.Errorf("got ip %q", config.Config.IP)
+	}
+	wantPorts := []portConfig{{Port: 0, Length: 5, StartPixel: 0}, {Port: 1, Length: 3, StartPixel: 5}}
+	if len(config.Config.Ports) != len(wantPorts) {
+		t.Fatalf("got ports %+v", config.Config.Ports)
+	}
+	for i := range wantPorts {
+		if config.Config.Ports[i] != wantPorts[i] {
+			t.Errorf("port %d: got %+v want %+v", i, config.Config.Ports[i], wantPorts[i])
+		}
+	}
+	setup.CheckFrames(t, nil)
+}
+
+func TestServe(t *testing.T) {
+	setup := receivetest.NewSetup(t)
+	r, err := NewReceiver(setup.Config, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, receiver := receivetest.Loopback(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan error, 1)
+	go func() {
+		served <- r.Serve(ctx, receiver)
+	}()
+	to := receiver.LocalAddr()
+	sender.WriteTo(buildPacket(flagVersion1, 0x0b, idDefault, 0, []byte{1, 2, 3}), to)
+	sender.WriteTo(buildPacket(flagVersion1|flagQuery, 0, idStatus, 0, nil), to)
+	sender.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err = sender.ReadFrom(make([]byte, 1500))
+	if err != nil {
+		t.Fatalf("no status reply: %v", err)
+	}
+	sender.WriteTo(buildPacket(flagVersion1|flagPush, 0x0b, idDefault, 5*3, []byte{4, 5, 6}), to)
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Serve did not return")
+	}
+	setup.CheckFrames(t, [][][]uint32{
+		{{0x010203, 0, 0, 0, 0}, {0x040506, 0, 0}},
+	})
+}
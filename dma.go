@@ -3,8 +3,6 @@ package rpiws281x
 import (
 	"os"
 	"time"
-
-	"github.com/DerLukas15/rpimemmap"
 )
 
 const (
@@ -53,7 +51,7 @@ var (
 	}
 )
 
-var dmaRegisterMem rpimemmap.MemMap //stores reference to dma device
+var dmaRegisterMem Memory //stores reference to dma device
 
 //Initialize the dma device
 func initializeDMA() error {
@@ -62,8 +60,8 @@ func initializeDMA() error {
 		logOutput("DMA already initialized. Skipping")
 		return nil
 	}
-	dmaRegisterMem = rpimemmap.NewPeripheral(uint32(os.Getpagesize()))
-	err := dmaRegisterMem.Map(registerDMABusOffset, rpimemmap.MemDevDefault, 0)
+	var err error
+	dmaRegisterMem, err = activeBackend.MapPeripheral(registerDMABusOffset, uint32(os.Getpagesize()))
 	if err != nil {
 		return err
 	}
@@ -76,7 +74,7 @@ func stopDMA(channel uint32) error {
 	if dmaRegisterMem == nil {
 		return nil
 	}
-	*rpimemmap.Reg32(dmaRegisterMem, registerOffsetDmaChannel(channel, registerOffsetDmaCs)) = registerValueDmaCsReset
+	dmaRegisterMem.Write32(registerOffsetDmaChannel(channel, registerOffsetDmaCs), registerValueDmaCsReset)
 	return nil
 }
 
@@ -85,7 +83,7 @@ func enableDMA(channel uint32) error {
 	if dmaRegisterMem == nil {
 		return nil
 	}
-	*rpimemmap.Reg32(dmaRegisterMem, registerOffsetDmaEnable) |= (1 << channel)
+	dmaRegisterMem.Write32(registerOffsetDmaEnable, dmaRegisterMem.Read32(registerOffsetDmaEnable)|(1<<channel))
 	return nil
 }
 
@@ -94,7 +92,7 @@ func disableDMA(channel uint32) error {
 	if dmaRegisterMem == nil {
 		return nil
 	}
-	*rpimemmap.Reg32(dmaRegisterMem, registerOffsetDmaEnable) &= ^(1 << channel)
+	dmaRegisterMem.Write32(registerOffsetDmaEnable, dmaRegisterMem.Read32(registerOffsetDmaEnable)&^(1<<channel))
 	return nil
 }
 
@@ -111,6 +109,14 @@ func cleanupDMA() error {
 	return nil
 }
 
+//returns true if the last transfer of the dma channel has ended
+func doneDMA(channel uint32) bool {
+	if dmaRegisterMem == nil {
+		return true
+	}
+	return dmaRegisterMem.Read32(registerOffsetDmaChannel(channel, registerOffsetDmaCs))&registerValueDmaCsEnd != 0
+}
+
 //starts a dma transfer with channel and dmaCBAddress
 func startDMA(channel uint32, dmaCBAddress uint32) error {
 	err := stopDMA(channel)
@@ -119,13 +125,13 @@ func startDMA(channel uint32, dmaCBAddress uint32) error {
 	}
 	//logOutput(fmt.Sprintf("DMA CB bus address: 0x%x", dmaCBAddress))
 	time.Sleep(10 * time.Microsecond)
-	*rpimemmap.Reg32(dmaRegisterMem, registerOffsetDmaChannel(channel, registerOffsetDmaCs)) = registerValueDmaCsInt | registerValueDmaCsEnd
+	dmaRegisterMem.Write32(registerOffsetDmaChannel(channel, registerOffsetDmaCs), registerValueDmaCsInt|registerValueDmaCsEnd)
 	time.Sleep(10 * time.Microsecond)
-	*rpimemmap.Reg32(dmaRegisterMem, registerOffsetDmaChannel(channel, registerOffsetDmaConblkAd)) = dmaCBAddress
-	*rpimemmap.Reg32(dmaRegisterMem, registerOffsetDmaChannel(channel, registerOffsetDmaDebug)) = 7
-	*rpimemmap.Reg32(dmaRegisterMem, registerOffsetDmaChannel(channel, registerOffsetDmaCs)) = registerValueDmaCsWaitOutstandingWrites |
-		registerValueDmaCsPanicPriority(15) | registerValueDmaCsPriority(15)
-	*rpimemmap.Reg32(dmaRegisterMem, registerOffsetDmaChannel(channel, registerOffsetDmaCs)) |= registerValueDmaCsActive
+	dmaRegisterMem.Write32(registerOffsetDmaChannel(channel, registerOffsetDmaConblkAd), dmaCBAddress)
+	dmaRegisterMem.Write32(registerOffsetDmaChannel(channel, registerOffsetDmaDebug), 7)
+	dmaRegisterMem.Write32(registerOffsetDmaChannel(channel, registerOffsetDmaCs), registerValueDmaCsWaitOutstandingWrites|
+		registerValueDmaCsPanicPriority(15)|registerValueDmaCsPriority(15))
+	dmaRegisterMem.Write32(registerOffsetDmaChannel(channel, registerOffsetDmaCs), dmaRegisterMem.Read32(registerOffsetDmaChannel(channel, registerOffsetDmaCs))|registerValueDmaCsActive)
 	time.Sleep(20 * time.Microsecond)
 	return nil
 }
@@ -16,6 +16,8 @@ const (
 	registerOffsetDmaCB2DModeStride   uint32 = 4 * 4
 	registerOffsetDmaCBNextCBAddress  uint32 = 5 * 4
 
+	dmaCBSize uint32 = 8 * 4 // Size of one dmaCB including the reserved words. Keeps the 256 bit alignment
+
 	//register values dmaCB Ti
 	registerValueDmaCBTiInten        uint32 = 1 << 0
 	registerValueDmaCBTiTdMode       uint32 = 1 << 1
@@ -44,29 +46,32 @@ var (
 	}
 )
 
-var dmaCBRegisterMemPWM rpimemmap.MemMap //stores reference to the one dmaCB
+var dmaCBRegisterMemPWM Memory //stores reference to the two dmaCBs. One for each buffer in pwmDataMem
 
 //initialize dmaCB storage for PWM
 func initializeDmaCBPWM(transferBytes uint32) error {
 	if dmaCBRegisterMemPWM != nil {
 		return nil
 	}
-	dmaCBRegisterMemPWM = rpimemmap.NewUncached(uint32(os.Getpagesize())) // will be rounded to next pageSize anyway
 	allocationFlags := rpimemmap.UncachedMemFlagDirect
 	if curHardware.RPiType == rpihardware.RPiType1 {
 		allocationFlags = 0xc
 	}
-	err := dmaCBRegisterMemPWM.Map(0, "", allocationFlags)
+	var err error
+	dmaCBRegisterMemPWM, err = activeBackend.MapUncached(uint32(os.Getpagesize()), allocationFlags) // will be rounded to next pageSize anyway
 	if err != nil {
 		return err
 	}
 	logOutput("DMA control block: " + dmaCBRegisterMemPWM.String())
-	*rpimemmap.Reg32(dmaCBRegisterMemPWM, registerOffsetDmaCBTi) = registerValueDmaCBTiNoWideBursts | registerValueDmaCBTiWaitResp | registerValueDmaCBTiDestDreq | registerValueDmaCBTiSrcInc | registerValueDmaCBTiPermap(5)
-	*rpimemmap.Reg32(dmaCBRegisterMemPWM, registerOffsetDmaCBSrcAddress) = pwmDataMem.BusAddr()
-	*rpimemmap.Reg32(dmaCBRegisterMemPWM, registerOffsetDmaCBDestAddress) = pwmRegisterMem.BusAddr() + registerOffsetPWMFif1
-	*rpimemmap.Reg32(dmaCBRegisterMemPWM, registerOffsetDmaCBTransferLength) = transferBytes
-	*rpimemmap.Reg32(dmaCBRegisterMemPWM, registerOffsetDmaCB2DModeStride) = 0
-	*rpimemmap.Reg32(dmaCBRegisterMemPWM, registerOffsetDmaCBNextCBAddress) = 0
+	for i := range pwmDataMem {
+		cbOffset := uint32(i) * dmaCBSize
+		dmaCBRegisterMemPWM.Write32(cbOffset+registerOffsetDmaCBTi, registerValueDmaCBTiNoWideBursts|registerValueDmaCBTiWaitResp|registerValueDmaCBTiDestDreq|registerValueDmaCBTiSrcInc|registerValueDmaCBTiPermap(5))
+		dmaCBRegisterMemPWM.Write32(cbOffset+registerOffsetDmaCBSrcAddress, pwmDataMem[i].BusAddr())
+		dmaCBRegisterMemPWM.Write32(cbOffset+registerOffsetDmaCBDestAddress, pwmRegisterMem.BusAddr()+registerOffsetPWMFif1)
+		dmaCBRegisterMemPWM.Write32(cbOffset+registerOffsetDmaCBTransferLength, transferBytes)
+		dmaCBRegisterMemPWM.Write32(cbOffset+registerOffsetDmaCB2DModeStride, 0)
+		dmaCBRegisterMemPWM.Write32(cbOffset+registerOffsetDmaCBNextCBAddress, 0)
+	}
 	return nil
 }
 
@@ -82,3 +87,42 @@ func cleanupDmaCBPWM() error {
 	dmaCBRegisterMemPWM = nil
 	return nil
 }
+
+var dmaCBRegisterMemPCM Memory //stores reference to the one dmaCB for PCM
+
+//initialize dmaCB storage for PCM
+func initializeDmaCBPCM(transferBytes uint32) error {
+	if dmaCBRegisterMemPCM != nil {
+		return nil
+	}
+	allocationFlags := rpimemmap.UncachedMemFlagDirect
+	if curHardware.RPiType == rpihardware.RPiType1 {
+		allocationFlags = 0xc
+	}
+	var err error
+	dmaCBRegisterMemPCM, err = activeBackend.MapUncached(uint32(os.Getpagesize()), allocationFlags) // will be rounded to next pageSize anyway
+	if err != nil {
+		return err
+	}
+	logOutput("DMA control block PCM: " + dmaCBRegisterMemPCM.String())
+	dmaCBRegisterMemPCM.Write32(registerOffsetDmaCBTi, registerValueDmaCBTiNoWideBursts|registerValueDmaCBTiWaitResp|registerValueDmaCBTiDestDreq|registerValueDmaCBTiSrcInc|registerValueDmaCBTiPermap(2))
+	dmaCBRegisterMemPCM.Write32(registerOffsetDmaCBSrcAddress, pcmDataMem.BusAddr())
+	dmaCBRegisterMemPCM.Write32(registerOffsetDmaCBDestAddress, pcmRegisterMem.BusAddr()+registerOffsetPCMFifo)
+	dmaCBRegisterMemPCM.Write32(registerOffsetDmaCBTransferLength, transferBytes)
+	dmaCBRegisterMemPCM.Write32(registerOffsetDmaCB2DModeStride, 0)
+	dmaCBRegisterMemPCM.Write32(registerOffsetDmaCBNextCBAddress, 0)
+	return nil
+}
+
+//deallocates dmaCB for pcm
+func cleanupDmaCBPCM() error {
+	if dmaCBRegisterMemPCM == nil {
+		return nil
+	}
+	err := dmaCBRegisterMemPCM.Unmap()
+	if err != nil {
+		return err
+	}
+	dmaCBRegisterMemPCM = nil
+	return nil
+}
@@ -0,0 +1,117 @@
+//Package e131 receives E1.31 (sACN) packets and writes the DMX data to the LEDs of a rpiws281x.Config.
+package e131
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// Errors
+var (
+	ErrWrongUniverse     = errors.New("universe has to be between 1 and 63999")
+	ErrWrongStartChannel = errors.New("start channel has to be between 1 and 512")
+	ErrWrongPatch        = errors.New("patch outside of the strip")
+	ErrNotWritable       = errors.New("strip is not writable. Set LEDs of the patch")
+	ErrNoPatches         = errors.New("no patches")
+)
+
+const (
+	Port         = 5568 // UDP port of E1.31
+	MaxUniverse  = 63999
+	UniverseSize = 512 // DMX channels of one universe
+
+	vectorRootData        = 0x00000004
+	vectorRootExtended    = 0x00000008
+	vectorFrameData       = 0x00000002
+	vectorFrameSync       = 0x00000001
+	vectorDMP             = 0x02
+	dmpAddressType        = 0xa1
+	optionPreview         = 0x80
+	optionTerminated      = 0x40
+	dataHeaderSize        = 126 // Bytes before the first DMX channel
+	syncPacketSize        = 49
+	sequenceDiscardWindow = -20 // Packets with a sequence difference in (-20, 0] are old
+)
+
+var acnPacketIdentifier = []byte("ASC-E1.17\x00\x00\x00")
+
+//CID is the component identifier of a source.
+type CID [16]byte
+
+//data packet of a universe
+type dataPacket struct {
+	cid         CID
+	priority    uint8
+	syncAddress uint16
+	sequence    uint8
+	options     uint8
+	universe    uint16
+	startCode   uint8
+	data        []byte // DMX channels without the start code
+}
+
+//synchronization packet
+type syncPacket struct {
+	cid         CID
+	sequence    uint8
+	syncAddress uint16
+}
+
+//checks the root layer and returns the root vector. 0 if the packet is not E1.31
+func rootVector(packet []byte) uint32 {
+	if len(packet) < 38 {
+		return 0
+	}
+	if binary.BigEndian.Uint16(packet[0:]) != 0x0010 || binary.BigEndian.Uint16(packet[2:]) != 0 ||
+		!bytes.Equal(packet[4:16], acnPacketIdentifier) {
+		return 0
+	}
+	return binary.BigEndian.Uint32(packet[18:])
+}
+
+//parses a data packet. ok is false if the packet is not valid
+func parseDataPacket(packet []byte) (res dataPacket, ok bool) {
+	if len(packet) < dataHeaderSize || rootVector(packet) != vectorRootData ||
+		binary.BigEndian.Uint32(packet[40:]) != vectorFrameData ||
+		packet[117] != vectorDMP || packet[118] != dmpAddressType {
+		return res, false
+	}
+	//The property count includes the start code
+	count := int(binary.BigEndian.Uint16(packet[123:]))
+	if count < 1 || count > UniverseSize+1 || dataHeaderSize-1+count > len(packet) {
+		return res, false
+	}
+	copy(res.cid[:], packet[22:38])
+	res.priority = packet[108]
+	res.syncAddress = binary.BigEndian.Uint16(packet[109:])
+	res.sequence = packet[111]
+	res.options = packet[112]
+	res.universe = binary.BigEndian.Uint16(packet[113:])
+	res.startCode = packet[125]
+	res.data = packet[dataHeaderSize : dataHeaderSize-1+count]
+	return res, true
+}
+
+//parses a synchronization packet. ok is false if the packet is not valid
+func parseSyncPacket(packet []byte) (res syncPacket, ok bool) {
+	if len(packet) < syncPacketSize || rootVector(packet) != vectorRootExtended ||
+		binary.BigEndian.Uint32(packet[40:]) != vectorFrameSync {
+		return res, false
+	}
+	copy(res.cid[:], packet[22:38])
+	res.sequence = packet[44]
+	res.syncAddress = binary.BigEndian.Uint16(packet[45:])
+	return res, true
+}
+
+//returns true if sequence is older than last
+func sequenceOld(sequence, last uint8) bool {
+	diff := int8(sequence - last)
+	return diff <= 0 && diff > sequenceDiscardWindow
+}
+
+//MulticastAddress returns the multicast group of universe. 239.255.hi.lo
+func MulticastAddress(universe uint16) [4]byte {
+	return [4]byte{239, 255, byte(universe >> 8), byte(universe)}
+}
@@ -0,0 +1,54 @@
+package e131
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+//ListenAndServe listens on the E1.31 port for unicast packets and joins the multicast groups of all patched universes
+//on ifi. The system default interface is used if ifi is nil. The packets are handled until ctx is done or an error occurs.
+//A done ctx is not an error.
+func (r *Receiver) ListenAndServe(ctx context.Context, ifi *net.Interface) error {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{Port: Port})
+	if err != nil {
+		return errors.Wrap(err, "receiver listen")
+	}
+	defer conn.Close()
+	err = joinGroups(conn, r.numbers, ifi)
+	if err != nil {
+		return errors.Wrap(err, "receiver listen")
+	}
+	return r.Serve(ctx, conn)
+}
+
+//joins the multicast groups of universes
+func joinGroups(conn *net.UDPConn, universes []uint16, ifi *net.Interface) error {
+	rawConn, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var joinErr error
+	err = rawConn.Control(func(fd uintptr) {
+		for _, curUniverse := range universes {
+			mreq := &unix.IPMreqn{
+				Multiaddr: MulticastAddress(curUniverse),
+			}
+			if ifi != nil {
+				mreq.Ifindex = int32(ifi.Index)
+			}
+			joinErr = unix.SetsockoptIPMreqn(int(fd), unix.IPPROTO_IP, unix.IP_ADD_MEMBERSHIP, mreq)
+			if joinErr != nil {
+				joinErr = errors.Wrap(joinErr, fmt.Sprintf("join universe %d", curUniverse))
+				return
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	return joinErr
+}
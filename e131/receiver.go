@@ -0,0 +1,256 @@
+package e131
+
+import (
+	"context"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/DerLukas15/rpiws281x"
+	"github.com/DerLukas15/rpiws281x/internal/receive"
+	"github.com/pkg/errors"
+)
+
+const sourceTimeout = 2500 * time.Millisecond // A source is lost after this time without data (network data loss)
+
+//Patch maps the DMX channels of a universe onto LEDs.
+type Patch struct {
+	Universe     uint16                 // Universe 1 to 63999
+	StartChannel int                    // DMX channel of the first slot of the first LED. 1 to 512
+	StripIndex   int                    // Strip of the Config. Its StripType selects 3 or 4 slots per LED and it is rendered after an update
+	LEDs         rpiws281x.WritableLEDs // LEDs which are written. The strip of StripIndex if nil
+	Position     int                    // Position of the first LED in LEDs
+	Count        int                    // Number of LEDs. 0 for all LEDs the universe holds after StartChannel
+}
+
+//a Patch with the values resolved from the Config
+type patch struct {
+	Patch
+	colorCount int
+}
+
+//a sender of a universe
+type source struct {
+	priority uint8
+	sequence uint8
+	lastSeen time.Time
+}
+
+//state of a patched universe
+type universe struct {
+	patches     []patch
+	sources     map[CID]*source
+	syncAddress uint16 // Synchronization universe of the last data
+	waiting     bool   // Data was written which is not rendered yet
+}
+
+//Receiver writes the data of E1.31 packets to the LEDs of a Config and renders them.
+/*
+The source with the highest priority of a universe wins. Data of several sources with the same priority is not merged,
+the last packet wins. Packets with an old sequence number, preview data and start codes other than 0 are ignored.
+
+Data with a synchronization address is rendered once the synchronization packet of that address is received. All other data
+is rendered once every patched universe was updated or if a universe is updated again before that.
+
+A Receiver is not safe for concurrent use. Render is called from the goroutine handling the packets.
+*/
+type Receiver struct {
+	config      *rpiws281x.Config
+	universes   map[uint16]*universe
+	numbers     []uint16 // Sorted patched universes
+	syncSources map[CID]uint8
+}
+
+//NewReceiver returns a Receiver writing to the strips of config as set by patches. The strips have to be set with
+//SetStrip before.
+func NewReceiver(config *rpiws281x.Config, patches []Patch) (*Receiver, error) {
+	if len(patches) == 0 {
+		return nil, errors.Wrap(ErrNoPatches, "new receiver")
+	}
+	r := &Receiver{
+		config:      config,
+		universes:   make(map[uint16]*universe),
+		syncSources: make(map[CID]uint8),
+	}
+	for _, curPatch := range patches {
+		if curPatch.Universe < 1 || curPatch.Universe > MaxUniverse {
+			return nil, errors.Wrap(ErrWrongUniverse, "new receiver")
+		}
+		if curPatch.StartChannel < 1 || curPatch.StartChannel > UniverseSize {
+			return nil, errors.Wrap(ErrWrongStartChannel, "new receiver")
+		}
+		strip, stripType, err := config.Strip(curPatch.StripIndex)
+		if err != nil {
+			return nil, errors.Wrap(err, "new receiver")
+		}
+		if curPatch.LEDs == nil {
+			writable, ok := strip.(rpiws281x.WritableLEDs)
+			if !ok {
+				return nil, errors.Wrap(ErrNotWritable, "new receiver")
+			}
+			curPatch.LEDs = writable
+		}
+		colorCount := stripType.ColorCount()
+		maxCount := (UniverseSize - curPatch.StartChannel + 1) / colorCount
+		if curPatch.Count == 0 {
+			curPatch.Count = maxCount
+			if curPatch.Position+curPatch.Count > curPatch.LEDs.TotalCount() {
+				curPatch.Count = curPatch.LEDs.TotalCount() - curPatch.Position
+			}
+		}
+		if curPatch.Position < 0 || curPatch.Count <= 0 || curPatch.Count > maxCount ||
+			curPatch.Position+curPatch.Count > curPatch.LEDs.TotalCount() {
+			return nil, errors.Wrap(ErrWrongPatch, "new receiver")
+		}
+		curUniverse, ok := r.universes[curPatch.Universe]
+		if !ok {
+			curUniverse = &universe{
+				sources: make(map[CID]*source),
+			}
+			r.universes[curPatch.Universe] = curUniverse
+			r.numbers = append(r.numbers, curPatch.Universe)
+		}
+		curUniverse.patches = append(curUniverse.patches, patch{Patch: curPatch, colorCount: colorCount})
+	}
+	sort.Slice(r.numbers, func(i, j int) bool { return r.numbers[i] < r.numbers[j] })
+	return r, nil
+}
+
+//Universes returns the patched universes in ascending order.
+func (r *Receiver) Universes() []uint16 {
+	return r.numbers
+}
+
+//Handle processes one E1.31 packet. Packets which are not E1.31 or not for a patched universe are ignored.
+//An error is only returned if Render fails.
+func (r *Receiver) Handle(packet []byte) error {
+	switch rootVector(packet) {
+	case vectorRootData:
+		data, ok := parseDataPacket(packet)
+		if !ok {
+			return nil
+		}
+		return r.handleData(data, time.Now())
+	case vectorRootExtended:
+		sync, ok := parseSyncPacket(packet)
+		if !ok {
+			return nil
+		}
+		if last, ok := r.syncSources[sync.cid]; ok && sequenceOld(sync.sequence, last) {
+			return nil
+		}
+		r.syncSources[sync.cid] = sync.sequence
+		return r.render(func(u *universe) bool { return u.syncAddress == sync.syncAddress })
+	}
+	return nil
+}
+
+//writes the data to the patches of the universe and renders if the frame is complete
+func (r *Receiver) handleData(data dataPacket, now time.Time) error {
+	curUniverse, ok := r.universes[data.universe]
+	if !ok || data.options&optionPreview != 0 {
+		return nil
+	}
+	curUniverse.expireSources(now)
+	curSource, ok := curUniverse.sources[data.cid]
+	if ok && sequenceOld(data.sequence, curSource.sequence) {
+		return nil
+	}
+	if data.options&optionTerminated != 0 {
+		delete(curUniverse.sources, data.cid)
+		return nil
+	}
+	if !ok {
+		curSource = &source{}
+		curUniverse.sources[data.cid] = curSource
+	}
+	curSource.priority = data.priority
+	curSource.sequence = data.sequence
+	curSource.lastSeen = now
+	for _, otherSource := range curUniverse.sources {
+		if otherSource.priority > data.priority {
+			return nil
+		}
+	}
+	if data.startCode != 0 {
+		return nil
+	}
+	//Show the previous frame of this universe before it is overwritten
+	if curUniverse.waiting && curUniverse.syncAddress == 0 && data.syncAddress == 0 {
+		err := r.render(func(u *universe) bool { return u.syncAddress == 0 })
+		if err != nil {
+			return err
+		}
+	}
+	for _, curPatch := range curUniverse.patches {
+		first := curPatch.StartChannel - 1
+		if first >= len(data.data) {
+			continue
+		}
+		last := first + curPatch.Count*curPatch.colorCount
+		if last > len(data.data) {
+			last = len(data.data)
+		}
+		rpiws281x.SetSlots(curPatch.LEDs, curPatch.Position, data.data[first:last], curPatch.colorCount)
+	}
+	curUniverse.syncAddress = data.syncAddress
+	curUniverse.waiting = true
+	if data.syncAddress != 0 {
+		return nil
+	}
+	for _, otherUniverse := range r.universes {
+		if !otherUniverse.waiting || otherUniverse.syncAddress != 0 {
+			return nil
+		}
+	}
+	return r.render(func(u *universe) bool { return u.syncAddress == 0 })
+}
+
+//removes the sources which did not send data within the timeout
+func (u *universe) expireSources(now time.Time) {
+	for curCID, curSource := range u.sources {
+		if now.Sub(curSource.lastSeen) > sourceTimeout {
+			delete(u.sources, curCID)
+		}
+	}
+}
+
+//renders the strips of all waiting universes selected by match
+func (r *Receiver) render(match func(u *universe) bool) error {
+	var set receive.RenderSet
+	for _, curUniverse := range r.universes {
+		if !curUniverse.waiting || !match(curUniverse) {
+			continue
+		}
+		curUniverse.waiting = false
+		for _, curPatch := range curUniverse.patches {
+			set.Add(curPatch.StripIndex)
+		}
+	}
+	err := set.Render(r.config)
+	if err != nil {
+		return errors.Wrap(err, "receiver render")
+	}
+	return nil
+}
+
+//Serve handles the packets received on conn until ctx is done or an error occurs. conn is not closed.
+//A done ctx is not an error.
+func (r *Receiver) Serve(ctx context.Context, conn net.PacketConn) error {
+	stop := receive.UnblockReads(ctx, conn)
+	defer stop()
+	buf := make([]byte, 1500)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "receiver serve")
+		}
+		err = r.Handle(buf[:n])
+		if err != nil {
+			return errors.Wrap(err, "receiver serve")
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package e131
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/DerLukas15/rpiws281x"
+	"github.com/DerLukas15/rpiws281x/internal/receivetest"
+)
+
+//builds a data packet
+func buildData(cid byte, universe uint16, priority, sequence, options uint8, syncAddress uint16, data []byte) []byte {
+	res := make([]byte, dataHeaderSize+len(data))
+	binary.BigEndian.PutUint16(res[0:], 0x0010)
+	copy(res[4:], acnPacketIdentifier)
+	binary.BigEndian.PutUint32(res[18:], vectorRootData)
+	res[22] = cid
+	binary.BigEndian.PutUint32(res[40:], vectorFrameData)
+	res[108] = priority
+	binary.BigEndian.PutUint16(res[109:], syncAddress)
+	res[111] = sequence
+	res[112] = options
+	binary.BigEndian.PutUint16(res[113:], universe)
+	res[117] = vectorDMP
+	res[118] = dmpAddressType
+	binary.BigEndian.PutUint16(res[123:], uint16(len(data)+1))
+	copy(res[dataHeaderSize:], data)
+	return res
+}
+
+//builds a synchronization packet
+func buildSync(cid byte, sequence uint8, syncAddress uint16) []byte {
+	res := make([]byte, syncPacketSize)
+	binary.BigEndian.PutUint16(res[0:], 0x0010)
+	copy(res[4:], acnPacketIdentifier)
+	binary.BigEndian.PutUint32(res[18:], vectorRootExtended)
+	res[22] = cid
+	binary.BigEndian.PutUint32(res[40:], vectorFrameSync)
+	res[44] = sequence
+	binary.BigEndian.PutUint16(res[45:], syncAddress)
+	return res
+}
+
+//sends packet over the loopback interface and handles it with r
+func deliver(t *testing.T, r *Receiver, sender net.PacketConn, receiver net.PacketConn, packet []byte) {
+	t.Helper()
+	data, _ := receivetest.Transfer(t, sender, receiver, packet)
+	err := r.Handle(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+//returns a Receiver with universe 1 on strip 0 and universe 2 on strip 1 and the last two LEDs of strip 0
+func newTestReceiver(t *testing.T, config *rpiws281x.Config) *Receiver {
+	t.Helper()
+	r, err := NewReceiver(config, []Patch{
+		{Universe: 1, StartChannel: 1, StripIndex: 0},
+		{Universe: 2, StartChannel: 1, StripIndex: 1},
+		{Universe: 2, StartChannel: 13, StripIndex: 0, Position: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestNewReceiver(t *testing.T) {
+	setup := receivetest.NewSetup(t)
+	r := newTestReceiver(t, setup.Config)
+	if universes := r.Universes(); len(universes) != 2 || universes[0] != 1 || universes[1] != 2 {
+		t.Errorf("got universes %v", universes)
+	}
+	//All LEDs of the strip after the start channel
+	if count := r.universes[2].patches[1].Count; count != 2 {
+		t.Errorf("got count %d want 2", count)
+	}
+	wrongPatches := []Patch{
+		{Universe: 0, StartChannel: 1},
+		{Universe: 1, StartChannel: 513},
+		{Universe: 1, StartChannel: 1, Position: 2, Count: 4},
+		{Universe: 1, StartChannel: 511, Count: 1},
+		{Universe: 1, StartChannel: 1, StripIndex: 2},
+	}
+	for _, curPatch := range wrongPatches {
+		_, err := NewReceiver(setup.Config, []Patch{curPatch})
+		if err == nil {
+			t.Errorf("%+v accepted", curPatch)
+		}
+	}
+}
+
+func TestReceiverSequenceAndPriority(t *testing.T) {
+	setup := receivetest.NewSetup(t)
+	r := newTestReceiver(t, setup.Config)
+	sender, receiver := receivetest.Loopback(t)
+	u := receivetest.Unrendered
+	//Rendered once all universes are updated
+	deliver(t, r, sender, receiver, buildData(1, 1, 100, 10, 0, 0, []byte{1, 2, 3, 4, 5, 6}))
+	deliver(t, r, sender, receiver, buildData(1, 2, 100, 10, 0, 0, []byte{7, 8, 9, 10, 0, 0, 0, 0, 0, 0, 0, 0, 11, 12, 13}))
+	//Old sequence of the same source
+	deliver(t, r, sender, receiver, buildData(1, 1, 100, 9, 0, 0, []byte{0xff, 0xff, 0xff}))
+	deliver(t, r, sender, receiver, buildData(1, 1, 100, 250, 0, 0, []byte{0xff, 0xff, 0xff}))
+	//Lower priority of another source
+	deliver(t, r, sender, receiver, buildData(2, 1, 50, 1, 0, 0, []byte{0xee, 0xee, 0xee}))
+	//Higher priority of another source wins
+	deliver(t, r, sender, receiver, buildData(3, 1, 150, 1, 0, 0, []byte{0x21, 0x22, 0x23}))
+	deliver(t, r, sender, receiver, buildData(1, 1, 100, 11, 0, 0, []byte{0xdd, 0xdd, 0xdd}))
+	//The repeated universe renders the previous data
+	deliver(t, r, sender, receiver, buildData(3, 1, 150, 2, 0, 0, []byte{0x31, 0x32, 0x33}))
+	//Preview data and other start codes are ignored
+	deliver(t, r, sender, receiver, buildData(3, 1, 150, 3, optionPreview, 0, []byte{0xcc}))
+	alternate := buildData(3, 1, 150, 4, 0, 0, []byte{0xcc})
+	alternate[dataHeaderSize-1] = 0xdd
+	deliver(t, r, sender, receiver, alternate)
+	//A terminated source is removed at once
+	deliver(t, r, sender, receiver, buildData(3, 1, 150, 5, optionTerminated, 0, nil))
+	deliver(t, r, sender, receiver, buildData(1, 1, 100, 12, 0, 0, []byte{0x41, 0x42, 0x43}))
+	deliver(t, r, sender, receiver, buildData(1, 2, 100, 11, 0, 0, []byte{0x51, 0x52, 0x53, 0x54}))
+	setup.CheckFrames(t, [][][]uint32{
+		{{0x010203, 0x040506, 0, 0x0b0c0d, 0}, {0x0a070809, 0, 0}},
+		{{0x212223, 0x040506, 0, 0x0b0c0d, 0}, {u, u, u}},
+		{{0x313233, 0x040506, 0, 0x0b0c0d, 0}, {u, u, u}},
+		{{0x414243, 0x040506, 0, 0x0b0c0d, 0}, {0x54515253, 0, 0}},
+	})
+}
+
+func TestReceiverSourceTimeout(t *testing.T) {
+	setup := receivetest.NewSetup(t)
+	r, err := NewReceiver(setup.Config, []Patch{{Universe: 1, StartChannel: 1, StripIndex: 0}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	handle := func(packet []byte, now time.Duration) {
+		data, ok := parseDataPacket(packet)
+		if !ok {
+			t.Fatal("invalid packet")
+		}
+		err := r.handleData(data, start.Add(now))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	handle(buildData(1, 1, 150, 1, 0, 0, []byte{1, 2, 3}), 0)
+	handle(buildData(2, 1, 100, 1, 0, 0, []byte{4, 5, 6}), time.Second)
+	handle(buildData(1, 1, 150, 2, 0, 0, []byte{7, 8, 9}), 2*time.Second)
+	//The source with the higher priority is lost
+	handle(buildData(2, 1, 100, 2, 0, 0, []byte{10, 11, 12}), 2*time.Second+sourceTimeout+time.Millisecond)
+	setup.CheckFrames(t, [][][]uint32{
+		{{0x010203, 0, 0, 0, 0}},
+		{{0x070809, 0, 0, 0, 0}},
+		{{0x0a0b0c, 0, 0, 0, 0}},
+	})
+}
+
+func TestReceiverSync(t *testing.T) {
+	setup := receivetest.NewSetup(t)
+	r := newTestReceiver(t, setup.Config)
+	sender, receiver := receivetest.Loopback(t)
+	u := receivetest.Unrendered
+	deliver(t, r, sender, receiver, buildData(1, 1, 100, 1, 0, 500, []byte{1, 2, 3}))
+	deliver(t, r, sender, receiver, buildData(1, 2, 100, 1, 0, 500, []byte{4, 5, 6, 7}))
+	//Neither complete universes nor other sync addresses render synchronized data
+	deliver(t, r, sender, receiver, buildSync(1, 1, 501))
+	setup.CheckFrames(t, nil)
+	deliver(t, r, sender, receiver, buildSync(1, 2, 500))
+	//Old sequence
+	deliver(t, r, sender, receiver, buildData(1, 1, 100, 2, 0, 500, []byte{8, 9, 10}))
+	deliver(t, r, sender, receiver, buildSync(1, 2, 500))
+	deliver(t, r, sender, receiver, buildSync(1, 3, 500))
+	setup.CheckFrames(t, [][][]uint32{
+		{{0x010203, 0, 0, 0, 0}, {0x07040506, 0, 0}},
+		{{0x08090a, 0, 0, 0, 0}, {u, u, u}},
+	})
+}
+
+func TestServe(t *testing.T) {
+	setup := receivetest.NewSetup(t)
+	r := newTestReceiver(t, setup.Config)
+	sender, receiver := receivetest.Loopback(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan error, 1)
+	go func() {
+		served <- r.Serve(ctx, receiver)
+	}()
+	sender.WriteTo(buildData(1, 1, 100, 1, 0, 0, []byte{1, 2, 3}), receiver.LocalAddr())
+	sender.WriteTo([]byte("not E1.31"), receiver.LocalAddr())
+	sender.WriteTo(buildData(1, 2, 100, 1, 0, 0, []byte{4, 5, 6, 7}), receiver.LocalAddr())
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Serve did not return")
+	}
+	setup.CheckFrames(t, [][][]uint32{
+		{{0x010203, 0, 0, 0, 0}, {0x07040506, 0, 0}},
+	})
+}
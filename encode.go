@@ -0,0 +1,161 @@
+package rpiws281x
+
+//returns the number of colors per LED for stripType
+func ledColorCount(stripType StripType) int {
+	// If our shift mask includes the highest nibble, then we have 4 LEDs, RBGW.
+	if (uint(stripType) & sk6812ShiftMask) != 0 {
+		return 4
+	}
+	return 3
+}
+
+//ColorCount returns the number of colors per LED. These are 3 or 4 (RGBW).
+func (s StripType) ColorCount() int {
+	return ledColorCount(s)
+}
+
+//returns the time in microseconds needed to send all LEDs of curChannel
+func channelProtocolTime(curChannel ledChannel, timing *symbolTiming) uint32 {
+	return uint32(float64(curChannel.strip.TotalCount()*ledColorCount(curChannel.stripType)*8) * timing.bitTime)
+}
+
+//colorPipeline holds the settings of a channel to get from the color of a LED to the output value.
+//Index of the arrays is the order on the wire.
+type colorPipeline struct {
+	shifts     [4]uint8
+	scales     [4]uint32       // Brightness and color correction. 1 - 256
+	curves     [4]*GammaCurve  // Gamma
+	ledScales  []uint32        // Segment brightness per LED. nil if there are no segments
+	whiteMode  WhiteMode       // WhiteNone for strips with 3 colors
+	whiteColor ColorCorrection // Color of the white LEDs
+}
+
+//returns the colorPipeline of the channel with ledCount LEDs
+func (ch *ledChannel) colorPipeline(ledCount int) colorPipeline {
+	var pipeline colorPipeline
+	pipeline.shifts = [4]uint8{ch.rshift, ch.gshift, ch.bshift, ch.wshift}
+	for j, curShift := range pipeline.shifts {
+		pipeline.scales[j] = ch.colorScale(curShift)
+		pipeline.curves[j] = ch.gammaCurve(curShift)
+	}
+	pipeline.ledScales = ch.segmentScales(ledCount)
+	if ledColorCount(ch.stripType) == 4 {
+		pipeline.whiteMode = ch.whiteMode
+		pipeline.whiteColor = ch.whiteColor
+	}
+	return pipeline
+}
+
+//returns the color of the LED with the white value set by the white mode
+func (p *colorPipeline) led(curLED uint32) uint32 {
+	if p.whiteMode == WhiteNone {
+		return curLED
+	}
+	return extractWhite(curLED, p.whiteMode, p.whiteColor)
+}
+
+//returns the scale (1 - 256) of the LED at position
+func (p *colorPipeline) ledScale(position int) uint32 {
+	if p.ledScales == nil {
+		return 256
+	}
+	return p.ledScales[position]
+}
+
+//returns the output value of color j of curLED
+func (p *colorPipeline) value(curLED uint32, ledScale uint32, j int) uint8 {
+	return p.curves[j][(((((curLED>>p.shifts[j])&0xff)*ledScale)>>8)*p.scales[j])>>8]
+}
+
+//encodes the symbols of all LEDs of curChannel into the cached buffer encoded of the channel.
+//If invert is set, the symbols are inverted by software.
+func encodeChannel(curChannel *ledChannel, timing *symbolTiming, invert bool) {
+	ledCount := curChannel.strip.TotalCount()
+	ledColors := ledColorCount(curChannel.stripType)
+	wordCount := (ledCount*ledColors*timing.format.Bits*8 + 31) / 32
+	if cap(curChannel.encoded) < wordCount {
+		curChannel.encoded = make([]uint32, wordCount)
+	}
+	words := curChannel.encoded[:wordCount]
+
+	pipeline := curChannel.colorPipeline(ledCount)
+	//The symbols of a color are added at once if they fit into pending. Otherwise in two halves
+	colorBits := uint(timing.format.Bits * 8)
+	chunkBits := colorBits
+	if colorBits > 32 {
+		chunkBits = colorBits / 2
+	}
+	chunkMask := uint64(1)<<chunkBits - 1
+	var invertMask uint64
+	if invert {
+		invertMask = uint64(1)<<colorBits - 1
+	}
+
+	var pending uint64 // Symbol bits not yet written to words. Only the lowest pendingBits are valid
+	var pendingBits uint
+	curWord := 0
+	for i := 0; i < ledCount; i++ {
+		curLED := pipeline.led(curChannel.strip.UInt32(i))
+		ledScale := pipeline.ledScale(i)
+		for j := 0; j < ledColors; j++ {
+			curColor := (uint32(pipeline.value(curLED, ledScale, j)) * curChannel.powerScale) >> 8
+			pattern := timing.table[curColor] ^ invertMask
+			for chunk := colorBits; chunk > 0; chunk -= chunkBits {
+				pending = pending<<chunkBits | (pattern>>(chunk-chunkBits))&chunkMask
+				pendingBits += chunkBits
+				if pendingBits >= 32 {
+					pendingBits -= 32
+					words[curWord] = uint32(pending >> pendingBits)
+					curWord++
+				}
+			}
+		}
+	}
+	if pendingBits > 0 {
+		words[curWord] = uint32(pending << (32 - pendingBits))
+		if invert {
+			//Keep the line high (idle) for the rest of the word
+			words[curWord] |= uint32(1)<<(32-pendingBits) - 1
+		}
+	}
+}
+
+//encodes the symbols of all LEDs of curChannel turned off into the cached buffer encoded of the channel.
+//The color settings, segments and power limits are not applied. If invert is set, the symbols are inverted by software.
+func encodeBlank(curChannel *ledChannel, timing *symbolTiming, invert bool) {
+	colorCount := curChannel.strip.TotalCount() * ledColorCount(curChannel.stripType)
+	colorBits := timing.format.Bits * 8
+	wordCount := (colorCount*colorBits + 31) / 32
+	if cap(curChannel.encoded) < wordCount {
+		curChannel.encoded = make([]uint32, wordCount)
+	}
+	words := curChannel.encoded[:wordCount]
+	for i := range words {
+		words[i] = 0
+	}
+	pattern := timing.table[0]
+	if invert {
+		pattern ^= uint64(1)<<uint(colorBits) - 1
+	}
+	bitPos := 0
+	for i := 0; i < colorCount; i++ {
+		for bit := colorBits - 1; bit >= 0; bit-- {
+			if (pattern>>uint(bit))&1 != 0 {
+				words[bitPos/32] |= 1 << uint(31-bitPos%32)
+			}
+			bitPos++
+		}
+	}
+	if invert && bitPos%32 != 0 {
+		//Keep the line high (idle) for the rest of the word
+		words[wordCount-1] |= uint32(1)<<uint(32-bitPos%32) - 1
+	}
+}
+
+//copies words to mem starting at word wordPos. Consecutive words are wordStep words apart.
+func writeWords(mem Memory, words []uint32, wordPos uint32, wordStep uint32) {
+	for _, curWord := range words {
+		mem.Write32(wordPos*4, curWord)
+		wordPos += wordStep
+	}
+}
@@ -0,0 +1,191 @@
+package rpiws281x
+
+import "testing"
+
+//returns a symbolTiming with the table of format
+func formatTiming(format SymbolFormat) *symbolTiming {
+	res := &symbolTiming{format: format}
+	for value := range res.table {
+		var pattern uint64
+		for k := 7; k >= 0; k-- {
+			pattern = pattern<<format.Bits | format.symbol(uint8(value>>k)&1)
+		}
+		res.table[value] = pattern
+	}
+	return res
+}
+
+//returns the color bytes of strip in the order on the wire of stripType
+func wireBytes(strip LEDs, stripType StripType) []uint8 {
+	shifts := []uint32{
+		uint32((stripType >> 16) & 0xff),
+		uint32((stripType >> 8) & 0xff),
+		uint32((stripType >> 0) & 0xff),
+		uint32((stripType >> 24) & 0xff),
+	}
+	var res []uint8
+	for i := 0; i < strip.TotalCount(); i++ {
+		for j := 0; j < stripType.ColorCount(); j++ {
+			res = append(res, uint8(strip.UInt32(i)>>shifts[j]))
+		}
+	}
+	return res
+}
+
+//returns a strip with 1000 LEDs for the benchmarks
+func benchmarkStrip() *LEDStrip {
+	res := NewLEDStrip(1000)
+	for i := 0; i < res.TotalCount(); i++ {
+		res.SetDirect(i, uint32(i)*2654435761)
+	}
+	return res
+}
+
+func TestEncodeChannelReference(t *testing.T) {
+	strip := NewLEDStrip(3)
+	strip.SetDirect(0, 0x00ff00)
+	strip.SetDirect(1, 0x5a12a5)
+	strip.SetDirect(2, 0x80017f)
+	rgbwStrip := NewLEDStrip(3)
+	rgbwStrip.SetDirect(0, 0xff000000)
+	rgbwStrip.SetDirect(1, 0x01020304)
+	rgbwStrip.SetDirect(2, 0x7f80aa55)
+	formats := []SymbolFormat{
+		DefaultSymbols,
+		{Bits: 4, ZeroHigh: 1, OneHigh: 3},
+		{Bits: 5, ZeroHigh: 1, OneHigh: 3}, // 40 bits per color are added in two chunks
+		{Bits: 5, ZeroHigh: 2, OneHigh: 4},
+	}
+	tests := []struct {
+		strip     *LEDStrip
+		stripType StripType
+	}{
+		{strip, WS2812Strip},
+		{rgbwStrip, SK6812StripGRBW},
+	}
+	for _, format := range formats {
+		timing := formatTiming(format)
+		for _, test := range tests {
+			for _, invert := range []bool{false, true} {
+				c, _ := New(DriverPWM)
+				err := c.SetStrip(test.strip, 18, test.stripType, 0, invert)
+				if err != nil {
+					t.Fatal(err)
+				}
+				c.SetBrightness(255, 0)
+				curChannel := &c.channels[0]
+				encodeChannel(curChannel, timing, invert)
+				want := referenceEncode(format, 0, wireBytes(test.strip, test.stripType), 0)
+				if invert {
+					//The rest of the last word stays high
+					want = invertWords(want)
+				}
+				if len(curChannel.encoded) != len(want) {
+					t.Errorf("%+v %x invert %v: got %d words want %d", format, test.stripType, invert, len(curChannel.encoded), len(want))
+					continue
+				}
+				for i := range want {
+					if curChannel.encoded[i] != want[i] {
+						t.Errorf("%+v %x invert %v word %d: got %032b want %032b", format, test.stripType, invert, i, curChannel.encoded[i], want[i])
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestEncodeBlank(t *testing.T) {
+	formats := []SymbolFormat{
+		DefaultSymbols,
+		{Bits: 5, ZeroHigh: 1, OneHigh: 3},
+	}
+	for _, format := range formats {
+		timing := formatTiming(format)
+		for _, stripType := range []StripType{WS2812Strip, SK6812StripGRBW} {
+			for _, invert := range []bool{false, true} {
+				c, _ := New(DriverPWM)
+				strip := NewLEDStrip(3)
+				strip.Fill(0xffffffff)
+				err := c.SetStrip(strip, 18, stripType, 0, invert)
+				if err != nil {
+					t.Fatal(err)
+				}
+				//Not applied to blank LEDs
+				c.SetGammaCurve(&GammaCurve{0: 0xff}, 0)
+				curChannel := &c.channels[0]
+				encodeChannel(curChannel, timing, invert)
+				encodeBlank(curChannel, timing, invert)
+				want := referenceEncode(format, 0, make([]uint8, 3*stripType.ColorCount()), 0)
+				if invert {
+					want = invertWords(want)
+				}
+				if len(curChannel.encoded) != len(want) {
+					t.Errorf("%+v %x invert %v: got %d words want %d", format, stripType, invert, len(curChannel.encoded), len(want))
+					continue
+				}
+				for i := range want {
+					if curChannel.encoded[i] != want[i] {
+						t.Errorf("%+v %x invert %v word %d: got %032b want %032b", format, stripType, invert, i, curChannel.encoded[i], want[i])
+					}
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkEncode(b *testing.B) {
+	c, _ := New(DriverPWM)
+	err := c.SetStrip(benchmarkStrip(), 18, WS2812Strip, 0, false)
+	if err != nil {
+		b.Fatal(err)
+	}
+	c.SetBrightness(255, 0)
+	timing, err := newSymbolTiming(TimingWS2812, simulatedHardware.OscFreq)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		encodeChannel(&c.channels[0], timing, false)
+	}
+}
+
+//BenchmarkEncodeReference encodes the same strip as BenchmarkEncode bit by bit for comparison.
+func BenchmarkEncodeReference(b *testing.B) {
+	strip := benchmarkStrip()
+	timing, err := newSymbolTiming(TimingWS2812, simulatedHardware.OscFreq)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		referenceEncode(timing.format, 0, wireBytes(strip, WS2812Strip), 0)
+	}
+}
+
+//BenchmarkRender measures the work of Render for PWM without waiting for the previous frame.
+func BenchmarkRender(b *testing.B) {
+	err := SetBackend(NewSimulatedBackend())
+	if err != nil {
+		b.Fatal(err)
+	}
+	c, _ := New(DriverPWM)
+	err = c.SetStrip(benchmarkStrip(), 18, WS2812Strip, 0, false)
+	if err != nil {
+		b.Fatal(err)
+	}
+	c.SetBrightness(255, 0)
+	err = c.Initialize()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer c.Stop()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.limitPower()
+		_, err = renderPWM(c.channels, -1, c.symbols)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package rpiws281x
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+//FrameFunc is called by Run before each frame to update the LEDs.
+//frame is the number of the frame since the start of Run. Dropped frames are counted as well so frame can be used as time base.
+type FrameFunc func(frame uint64) error
+
+//FrameStats contains the statistics of a frame loop started with Run.
+type FrameStats struct {
+	Frames   uint64 // Number of rendered frames
+	Dropped  uint64 // Number of frames skipped because the loop fell behind
+	Overruns uint64 // Number of frames which took longer than the frame time
+}
+
+//Run renders the strip with index stripIndex (-1 for all) with a fixed frame rate of fps until ctx is done or an error occurs.
+/*
+update is called before each frame. If the Config is not initialized, Run initializes it. Once Run returns, the Config
+is stopped with Stop in every case.
+
+The frame time can't be shorter than the time needed to send the LEDs. If a frame takes longer than the frame time, an overrun is counted.
+Frames which can't be rendered in time are dropped so the loop keeps the pace of fps.
+
+A done ctx is not an error.
+*/
+func (c *Config) Run(ctx context.Context, fps float64, stripIndex int, update FrameFunc) (FrameStats, error) {
+	var stats FrameStats
+	if fps <= 0 {
+		return stats, errors.Wrap(ErrWrongFPS, "config Run")
+	}
+	err := c.Initialize()
+	if err != nil {
+		return stats, errors.Wrap(err, "config Run")
+	}
+	err = c.runFrames(ctx, time.Duration(float64(time.Second)/fps), stripIndex, update, &stats)
+	//Let the last frame finish before stopping
+	c.Wait(context.Background())
+	stopErr := c.Stop()
+	if err != nil {
+		return stats, errors.Wrap(err, "config Run")
+	}
+	if stopErr != nil {
+		return stats, errors.Wrap(stopErr, "config Run")
+	}
+	return stats, nil
+}
+
+//renders frames until ctx is done or an error occurs
+func (c *Config) runFrames(ctx context.Context, frameTime time.Duration, stripIndex int, update FrameFunc, stats *FrameStats) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+	var frame uint64
+	nextFrame := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+		frameStart := time.Now()
+		err := update(frame)
+		if err != nil {
+			return err
+		}
+		c.mu.Lock()
+		err = c.render(stripIndex)
+		renderWaitTime := c.renderWaitTime
+		c.mu.Unlock()
+		if err != nil {
+			return err
+		}
+		stats.Frames++
+		frame++
+		//Frame time can't be shorter than sending the LEDs
+		curFrameTime := frameTime
+		if minFrameTime := time.Duration(renderWaitTime) * time.Microsecond; curFrameTime < minFrameTime {
+			curFrameTime = minFrameTime
+		}
+		if time.Since(frameStart) > curFrameTime {
+			stats.Overruns++
+		}
+		nextFrame = nextFrame.Add(curFrameTime)
+		if behind := time.Since(nextFrame); behind > 0 {
+			dropped := uint64(behind / curFrameTime)
+			stats.Dropped += dropped
+			frame += dropped
+			nextFrame = nextFrame.Add(time.Duration(dropped) * curFrameTime)
+		}
+		timer.Reset(time.Until(nextFrame))
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-timer.C:
+		}
+	}
+}
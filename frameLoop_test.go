@@ -0,0 +1,83 @@
+package rpiws281x
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestRunStats(t *testing.T) {
+	strip := testStrip(8, false)
+	c, backend := newPWMTestConfig(t, strip, WS2812Strip)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var frames []uint64
+	stats, err := c.Run(ctx, 50, -1, func(frame uint64) error {
+		frames = append(frames, frame)
+		strip.SetDirect(0, uint32(frame))
+		switch len(frames) {
+		case 3:
+			//Overrun by more than two frames
+			time.Sleep(70 * time.Millisecond)
+		case 6:
+			cancel()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Frames != 6 || len(frames) != 6 {
+		t.Fatalf("got %d frames and %d updates want 6", stats.Frames, len(frames))
+	}
+	if stats.Overruns < 1 {
+		t.Errorf("got %d overruns want at least 1", stats.Overruns)
+	}
+	if stats.Dropped < 2 {
+		t.Errorf("got %d dropped frames want at least 2", stats.Dropped)
+	}
+	//Dropped frames are counted in the frame numbers
+	for i := 1; i < 3; i++ {
+		if frames[i] != frames[i-1]+1 {
+			t.Errorf("frames before the overrun: got %v", frames)
+		}
+	}
+	if last := frames[len(frames)-1]; last != stats.Frames+stats.Dropped-1 {
+		t.Errorf("last frame: got %d want %d", last, stats.Frames+stats.Dropped-1)
+	}
+	decoded, err := DecodePWM(backend.LastTransfer(c.dmaChannel), c.PWMLayout())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDecoded(t, decoded, strip)
+	//Run stops the Config
+	err = c.Render(-1)
+	if errors.Cause(err) != ErrConfigNotInitialized {
+		t.Errorf("Render after Run: got %v want %v", err, ErrConfigNotInitialized)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	c, _ := newPWMTestConfig(t, testStrip(8, false), WS2812Strip)
+	_, err := c.Run(context.Background(), 0, -1, func(uint64) error { return nil })
+	if errors.Cause(err) != ErrWrongFPS {
+		t.Errorf("fps 0: got %v want %v", err, ErrWrongFPS)
+	}
+	errUpdate := errors.New("update failed")
+	var updates int
+	stats, err := c.Run(context.Background(), 1000, -1, func(uint64) error {
+		updates++
+		if updates == 3 {
+			return errUpdate
+		}
+		return nil
+	})
+	if errors.Cause(err) != errUpdate {
+		t.Errorf("update: got %v want %v", err, errUpdate)
+	}
+	if stats.Frames != 2 {
+		t.Errorf("update: got %d frames want 2", stats.Frames)
+	}
+}
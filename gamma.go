@@ -0,0 +1,86 @@
+package rpiws281x
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+//GammaCurve maps a color value (after brightness) to the value sent to the LEDs.
+type GammaCurve [256]uint8
+
+//ColorComponent selects a single color of a LED.
+type ColorComponent uint8
+
+//Valid ColorComponents. The value is the byte position in the format 0xWWRRGGBB.
+const (
+	ColorBlue ColorComponent = iota
+	ColorGreen
+	ColorRed
+	ColorWhite
+)
+
+//Gamma presets to be used with SetGamma
+const (
+	GammaLinear  = 1.0 // No correction. Default
+	GammaWS2812B = 2.8 // Perceptually linear for WS2812B
+	GammaSK6812  = 2.2 // Perceptually linear for SK6812 (RGB and RGBW)
+)
+
+var linearGammaCurve = NewGammaCurve(GammaLinear)
+
+//NewGammaCurve returns a GammaCurve for the exponent gamma: out = 255 * (in / 255) ^ gamma
+func NewGammaCurve(gamma float64) *GammaCurve {
+	var res GammaCurve
+	for x := range res {
+		res[x] = uint8(math.Round(math.Pow(float64(x)/255, gamma) * 255))
+	}
+	return &res
+}
+
+//SetGamma sets the gamma exponent for all colors of the strip with index stripIndex. See the Gamma constants for presets.
+//This method can be called once the Config is initialized.
+func (c *Config) SetGamma(gamma float64, stripIndex int) error {
+	if gamma <= 0 {
+		return errors.Wrap(ErrWrongGamma, "config SetGamma")
+	}
+	return c.SetGammaCurve(NewGammaCurve(gamma), stripIndex)
+}
+
+//SetGammaCurve sets a custom curve for all colors of the strip with index stripIndex. nil resets to linear.
+//This method can be called once the Config is initialized.
+func (c *Config) SetGammaCurve(curve *GammaCurve, stripIndex int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if stripIndex < 0 || stripIndex >= len(c.channels) {
+		return errors.Wrap(ErrConfigWrongIndex, "config SetGammaCurve")
+	}
+	for i := range c.channels[stripIndex].gamma {
+		c.channels[stripIndex].gamma[i] = curve
+	}
+	return nil
+}
+
+//SetComponentGammaCurve sets a custom curve for a single color of the strip with index stripIndex. nil resets to linear.
+//This method can be called once the Config is initialized.
+func (c *Config) SetComponentGammaCurve(curve *GammaCurve, component ColorComponent, stripIndex int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if stripIndex < 0 || stripIndex >= len(c.channels) {
+		return errors.Wrap(ErrConfigWrongIndex, "config SetComponentGammaCurve")
+	}
+	if component > ColorWhite {
+		return errors.Wrap(ErrWrongColorComponent, "config SetComponentGammaCurve")
+	}
+	c.channels[stripIndex].gamma[component] = curve
+	return nil
+}
+
+//returns the gamma curve for the color at shift in 0xWWRRGGBB
+func (ch *ledChannel) gammaCurve(shift uint8) *GammaCurve {
+	curve := ch.gamma[(shift/8)&0x3]
+	if curve == nil {
+		return linearGammaCurve
+	}
+	return curve
+}
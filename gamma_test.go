@@ -0,0 +1,113 @@
+package rpiws281x
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestNewGammaCurve(t *testing.T) {
+	for _, gamma := range []float64{0.5, GammaLinear, GammaSK6812, GammaWS2812B, 4} {
+		curve := NewGammaCurve(gamma)
+		if curve[0] != 0 || curve[255] != 255 {
+			t.Errorf("gamma %v: got endpoints %d and %d want 0 and 255", gamma, curve[0], curve[255])
+		}
+		for x := 1; x < len(curve); x++ {
+			if curve[x] < curve[x-1] {
+				t.Errorf("gamma %v: not monotonic at %d: %d < %d", gamma, x, curve[x], curve[x-1])
+				break
+			}
+		}
+	}
+	linear := NewGammaCurve(GammaLinear)
+	for x := range linear {
+		if linear[x] != uint8(x) {
+			t.Fatalf("linear: got %d for %d", linear[x], x)
+		}
+	}
+	tests := []struct {
+		gamma float64
+		in    uint8
+		want  uint8
+	}{
+		{2, 128, 64},
+		{2, 16, 1},
+		{GammaWS2812B, 128, 37},
+		{0.5, 64, 128},
+	}
+	for _, test := range tests {
+		if got := NewGammaCurve(test.gamma)[test.in]; got != test.want {
+			t.Errorf("gamma %v of %d: got %d want %d", test.gamma, test.in, got, test.want)
+		}
+	}
+}
+
+func TestSetGammaErrors(t *testing.T) {
+	c, _ := New(DriverPWM)
+	err := c.SetStrip(NewLEDStrip(1), 18, WS2812Strip, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"gamma 0", c.SetGamma(0, 0), ErrWrongGamma},
+		{"negative gamma", c.SetGamma(-1, 0), ErrWrongGamma},
+		{"SetGamma index", c.SetGamma(GammaWS2812B, 2), ErrConfigWrongIndex},
+		{"SetGammaCurve index", c.SetGammaCurve(nil, -1), ErrConfigWrongIndex},
+		{"SetComponentGammaCurve index", c.SetComponentGammaCurve(nil, ColorRed, 2), ErrConfigWrongIndex},
+		{"component", c.SetComponentGammaCurve(nil, ColorWhite+1, 0), ErrWrongColorComponent},
+	}
+	for _, test := range tests {
+		if errors.Cause(test.err) != test.want {
+			t.Errorf("%s: got %v want %v", test.name, test.err, test.want)
+		}
+	}
+}
+
+func TestGammaRender(t *testing.T) {
+	strip := NewLEDStrip(2)
+	strip.SetDirect(0, 0x80808080)
+	strip.SetDirect(1, 0x40c0ff10)
+	c, backend := newPWMTestConfig(t, strip, SK6812StripGRBW)
+	square := NewGammaCurve(2)
+	apply := func(led uint32, curves [4]*GammaCurve) uint32 {
+		var res uint32
+		for component, curve := range curves {
+			val := uint8(led >> (8 * component))
+			if curve != nil {
+				val = curve[val]
+			}
+			res |= uint32(val) << (8 * component)
+		}
+		return res
+	}
+	tests := []struct {
+		name  string
+		setup func() error
+		want  [4]*GammaCurve
+	}{
+		{"linear", func() error { return nil }, [4]*GammaCurve{}},
+		{"all components", func() error { return c.SetGamma(2, 0) }, [4]*GammaCurve{square, square, square, square}},
+		{"reset", func() error { return c.SetGammaCurve(nil, 0) }, [4]*GammaCurve{}},
+		{"red", func() error { return c.SetComponentGammaCurve(square, ColorRed, 0) }, [4]*GammaCurve{ColorRed: square}},
+		{"red and white", func() error { return c.SetComponentGammaCurve(square, ColorWhite, 0) },
+			[4]*GammaCurve{ColorRed: square, ColorWhite: square}},
+		{"reset red", func() error { return c.SetComponentGammaCurve(nil, ColorRed, 0) }, [4]*GammaCurve{ColorWhite: square}},
+	}
+	for _, test := range tests {
+		err := test.setup()
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		decoded := renderDecoded(t, c, backend)
+		for i := 0; i < strip.TotalCount(); i++ {
+			want := apply(strip.UInt32(i), test.want)
+			if got := decoded[0].UInt32(i); got != want {
+				t.Errorf("%s: LED %d: got %x want %x", test.name, i, got, want)
+			}
+		}
+	}
+}
@@ -5,11 +5,9 @@ go 1.17
 require (
 	github.com/DerLukas15/rpigpio v1.0.0
 	github.com/DerLukas15/rpihardware v1.0.2
+	github.com/DerLukas15/rpimemmap v1.0.1
 	github.com/pkg/errors v0.9.1
+	golang.org/x/sys v0.0.0-20220227234510-4e6760a101f9
 )
 
-require (
-	github.com/DerLukas15/rpimemmap v1.0.1 // indirect
-	github.com/dswarbrick/smart v0.0.0-20190505152634-909a45200d6d // indirect
-	golang.org/x/sys v0.0.0-20220227234510-4e6760a101f9 // indirect
-)
+require github.com/dswarbrick/smart v0.0.0-20190505152634-909a45200d6d // indirect
@@ -0,0 +1,67 @@
+//Package receive holds the parts shared by the network receivers of the subpackages.
+package receive
+
+import (
+	"context"
+	"time"
+
+	"github.com/DerLukas15/rpiws281x"
+	"github.com/pkg/errors"
+)
+
+//RenderSet collects the strips changed by a receiver and renders them with one Render. The zero value is an empty set.
+type RenderSet struct {
+	stripIndex int
+	added      bool
+}
+
+//Add adds the strip with index stripIndex.
+func (s *RenderSet) Add(stripIndex int) {
+	if !s.added {
+		s.stripIndex = stripIndex
+		s.added = true
+		return
+	}
+	if s.stripIndex != stripIndex {
+		s.stripIndex = -1
+	}
+}
+
+//StripIndex returns the stripIndex for Render: the index of the only added strip or -1 if different strips were added.
+//ok is false for an empty set.
+func (s *RenderSet) StripIndex() (stripIndex int, ok bool) {
+	return s.stripIndex, s.added
+}
+
+//Render renders the added strips of config and empties the set. Nothing is rendered for an empty set.
+func (s *RenderSet) Render(config *rpiws281x.Config) error {
+	stripIndex, ok := s.StripIndex()
+	if !ok {
+		return nil
+	}
+	*s = RenderSet{}
+	err := config.Render(stripIndex)
+	if err != nil {
+		return errors.Wrap(err, "RenderSet Render")
+	}
+	return nil
+}
+
+//ReadDeadliner is a connection with a read deadline like net.Conn and net.PacketConn.
+type ReadDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+//UnblockReads sets a read deadline in the past on conn once ctx is done. This returns blocked reads of a receiver.
+//The returned stop function ends the watch and has to be called once conn is no longer read.
+func UnblockReads(ctx context.Context, conn ReadDeadliner) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetReadDeadline(time.Now())
+		case <-done:
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,60 @@
+package receive
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestRenderSet(t *testing.T) {
+	tests := []struct {
+		add  []int
+		want int
+		ok   bool
+	}{
+		{nil, 0, false},
+		{[]int{1}, 1, true},
+		{[]int{1, 1}, 1, true},
+		{[]int{0, 1}, -1, true},
+		{[]int{1, 0, 1}, -1, true},
+	}
+	for i, test := range tests {
+		var set RenderSet
+		for _, stripIndex := range test.add {
+			set.Add(stripIndex)
+		}
+		got, ok := set.StripIndex()
+		if ok != test.ok || (ok && got != test.want) {
+			t.Errorf("%d: got %d, %v want %d, %v", i, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestUnblockReads(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	stop := UnblockReads(ctx, conn)
+	defer stop()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := conn.ReadFrom(make([]byte, 1))
+		done <- err
+	}()
+	select {
+	case err = <-done:
+		if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+			t.Errorf("got %v want timeout", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("read not unblocked")
+	}
+}
@@ -0,0 +1,153 @@
+//Package receivetest provides the fixtures for the tests of the network receivers.
+package receivetest
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/DerLukas15/rpiws281x"
+)
+
+const (
+	Unrendered uint32 = 0xdeadbeef // Color of LEDs which were not part of a frame
+	DMAChannel        = 10         // DMA channel of the Config of a Setup
+)
+
+//Setup is an initialized PWM config with a RGB strip with 5 LEDs (index 0) and a RGBW strip with 3 LEDs (index 1)
+//on the simulated backend. All rendered frames are recorded.
+type Setup struct {
+	Config    *rpiws281x.Config
+	Backend   *rpiws281x.SimulatedBackend
+	recording bytes.Buffer
+	recorder  *rpiws281x.Recorder
+}
+
+//NewSetup returns a Setup which is stopped at the end of the test.
+func NewSetup(t *testing.T) *Setup {
+	t.Helper()
+	s := &Setup{Backend: rpiws281x.NewSimulatedBackend()}
+	err := rpiws281x.SetBackend(s.Backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Config, _ = rpiws281x.New(rpiws281x.DriverPWM)
+	err = s.Config.SetDMAChannel(DMAChannel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Config.SetStrip(rpiws281x.NewLEDStrip(5), 18, rpiws281x.WS2812Strip, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Config.SetStrip(rpiws281x.NewLEDStrip(3), 13, rpiws281x.SK6812StripGRBW, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Config.Initialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Config.Stop() })
+	s.recorder = rpiws281x.NewRecorder(&s.recording)
+	s.Config.SetRecorder(s.recorder)
+	return s
+}
+
+//Frames returns the colors of each recorded frame per strip. LEDs of strips which were not rendered are Unrendered.
+func (s *Setup) Frames(t *testing.T) [][][]uint32 {
+	t.Helper()
+	err := s.recorder.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.recording.Len() == 0 {
+		return nil
+	}
+	p, err := rpiws281x.NewPlayer(bytes.NewReader(s.recording.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res [][][]uint32
+	for frame := 0; frame < p.FrameCount(); frame++ {
+		var strips []rpiws281x.WritableLEDs
+		for _, curChannel := range p.Channels() {
+			strip := rpiws281x.NewLEDStrip(curChannel.LEDCount)
+			strip.Fill(Unrendered)
+			strips = append(strips, strip)
+		}
+		err = p.ReadFrame(frame, strips)
+		if err != nil {
+			t.Fatal(err)
+		}
+		colors := make([][]uint32, len(strips))
+		for i, curStrip := range strips {
+			for position := 0; position < curStrip.TotalCount(); position++ {
+				colors[i] = append(colors[i], curStrip.UInt32(position))
+			}
+		}
+		res = append(res, colors)
+	}
+	return res
+}
+
+//CheckFrames checks the recorded frames against want.
+func (s *Setup) CheckFrames(t *testing.T, want [][][]uint32) {
+	t.Helper()
+	frames := s.Frames(t)
+	if len(frames) != len(want) {
+		t.Fatalf("got %d frames want %d", len(frames), len(want))
+	}
+	for i := range want {
+		for stripIndex := range want[i] {
+			if !EqualColors(frames[i][stripIndex], want[i][stripIndex]) {
+				t.Errorf("frame %d strip %d: got %x want %x", i, stripIndex, frames[i][stripIndex], want[i][stripIndex])
+			}
+		}
+	}
+}
+
+//EqualColors reports whether a and b contain the same colors.
+func EqualColors(a, b []uint32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+//Loopback returns two connections on the loopback interface which are closed at the end of the test.
+func Loopback(t *testing.T) (sender net.PacketConn, receiver net.PacketConn) {
+	t.Helper()
+	var conns [2]net.PacketConn
+	for i := range conns {
+		conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		conns[i] = conn
+	}
+	return conns[0], conns[1]
+}
+
+//Transfer sends packet from sender to receiver and returns the packet and its source as read by receiver.
+func Transfer(t *testing.T, sender net.PacketConn, receiver net.PacketConn, packet []byte) ([]byte, net.Addr) {
+	t.Helper()
+	_, err := sender.WriteTo(packet, receiver.LocalAddr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 1500)
+	receiver.SetReadDeadline(time.Now().Add(time.Second))
+	n, addr, err := receiver.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf[:n], addr
+}
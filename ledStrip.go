@@ -3,109 +3,144 @@ package rpiws281x
 import "image/color"
 
 //LEDStrip represent a physical continious strip of LEDs.
-//Each LED is represented by a SingleLED.
+//The colors of all LEDs are stored in one contiguous slice with the format 0xWWRRGGBB (see SingleLED).
 type LEDStrip struct {
-	leds  []*SingleLED
-	count int
+	leds []uint32
 }
 
 //NewLEDStrip returns a LEDStrip with count LEDs. Colors can be set with the methods.
 func NewLEDStrip(count int) *LEDStrip {
-	l := &LEDStrip{}
-	l.leds = make([]*SingleLED, count, count)
-	for i := 0; i < count; i++ {
-		var led SingleLED
-		l.leds[i] = &led
+	if count < 0 {
+		count = 0
+	}
+	return &LEDStrip{
+		leds: make([]uint32, count),
 	}
-	l.count = count
-	return l
 }
 
 //TotalCount returns the number of LEDs in the strip.
 func (l *LEDStrip) TotalCount() int {
-	return l.count
+	return len(l.leds)
 }
 
 //Red returns the red color amount at position.
 func (l *LEDStrip) Red(position int) uint8 {
-	if position < 0 || position >= l.count {
-		return 0
-	}
-	return l.leds[position].Red(0)
+	return uint8(l.UInt32(position) >> 16)
 }
 
 //Green returns the green color amount at position.
 func (l *LEDStrip) Green(position int) uint8 {
-	if position < 0 || position >= l.count {
-		return 0
-	}
-	return l.leds[position].Green(0)
+	return uint8(l.UInt32(position) >> 8)
 }
 
 //Blue returns the blue color amount at position.
 func (l *LEDStrip) Blue(position int) uint8 {
-	if position < 0 || position >= l.count {
-		return 0
-	}
-	return l.leds[position].Blue(0)
+	return uint8(l.UInt32(position))
 }
 
 //White returns the white color amount at position.
 func (l *LEDStrip) White(position int) uint8 {
-	if position < 0 || position >= l.count {
-		return 0
-	}
-	return l.leds[position].White(0)
+	return uint8(l.UInt32(position) >> 24)
 }
 
 //SetColor sets the color from color.Color at position.
 func (l *LEDStrip) SetColor(position int, c color.Color) {
-	if position < 0 || position >= l.count {
+	if position < 0 || position >= len(l.leds) {
 		return
 	}
-	l.leds[position].SetColor(c)
+	l.leds[position] = colorToUInt32(c)
 }
 
 //SetRGBA sets the color value by r, g, b, and a values at position.
 func (l *LEDStrip) SetRGBA(position int, r, g, b, a uint32) {
-	if position < 0 || position >= l.count {
+	if position < 0 || position >= len(l.leds) {
 		return
 	}
-	l.leds[position].SetRGBA(r, g, b, a)
+	l.leds[position] = a<<24 | r<<16 | g<<8 | b
 }
 
 //SetDirect sets the color value for LED at position directly. Format 0xWWRRGGBB
 func (l *LEDStrip) SetDirect(position int, val uint32) {
-	if position < 0 || position >= l.count {
+	if position < 0 || position >= len(l.leds) {
 		return
 	}
-	l.leds[position].SetDirect(val)
+	l.leds[position] = val
 }
 
 //UInt32 returns the color as uint32. Format 0xWWRRGGBB
 func (l *LEDStrip) UInt32(position int) uint32 {
-	if position < 0 || position >= l.count {
+	if position < 0 || position >= len(l.leds) {
 		return 0
 	}
-	return l.leds[position].UInt32(0)
+	return l.leds[position]
+}
+
+//Fill sets all LEDs to val. Format 0xWWRRGGBB
+func (l *LEDStrip) Fill(val uint32) {
+	for i := range l.leds {
+		l.leds[i] = val
+	}
+}
+
+//FillColor sets all LEDs to the color from color.Color.
+func (l *LEDStrip) FillColor(c color.Color) {
+	l.Fill(colorToUInt32(c))
+}
+
+//CopyFrom sets the LEDs to the colors of src starting at position 0. If the sizes differ, only the smaller number of LEDs is copied.
+func (l *LEDStrip) CopyFrom(src LEDs) {
+	if srcStrip, ok := src.(*LEDStrip); ok {
+		copy(l.leds, srcStrip.leds)
+		return
+	}
+	count := src.TotalCount()
+	if count > len(l.leds) {
+		count = len(l.leds)
+	}
+	for i := 0; i < count; i++ {
+		l.leds[i] = src.UInt32(i)
+	}
+}
+
+//Slice returns the colors of all LEDs with the format 0xWWRRGGBB. This is no copy. Changes are applied to the strip directly.
+func (l *LEDStrip) Slice() []uint32 {
+	return l.leds
 }
 
 //ShiftRight shifts the LED colors by shift to the right. Everything leaving on the right wraps around.
 //Use ShiftLeft instead of negative shitfs.
 func (l *LEDStrip) ShiftRight(shift int) {
-	if shift <= 0 || (shift%l.count) == 0 { //Don't need to shift on <=0 and when multiple of count
+	if shift <= 0 || len(l.leds) == 0 || (shift%len(l.leds)) == 0 { //Don't need to shift on <=0 and when multiple of count
 		return
 	}
-	actualShift := shift % l.count
-	l.leds = append(l.leds[l.count-actualShift:], l.leds[0:l.count-actualShift]...)
+	l.rotateLeft(len(l.leds) - shift%len(l.leds))
 }
 
 //ShiftLeft shifts the LED colors by shift to the left. Everything leaving on the left wraps around.
 //Use ShiftRight instead of negative shitfs.
 func (l *LEDStrip) ShiftLeft(shift int) {
-	if shift <= 0 || (shift%l.count) == 0 { //Don't need to shift on <=0 and when multiple of count
+	if shift <= 0 || len(l.leds) == 0 || (shift%len(l.leds)) == 0 { //Don't need to shift on <=0 and when multiple of count
 		return
 	}
-	actualShift := shift % l.count
-	l.leds = append(l.leds[actualShift+1:], l.leds[0:actualShift]...)
+	l.rotateLeft(shift % len(l.leds))
+}
+
+//rotates the leds in place by shift (0 < shift < count) to the left by reversing both parts and then the whole slice
+func (l *LEDStrip) rotateLeft(shift int) {
+	reverseLEDs(l.leds[:shift])
+	reverseLEDs(l.leds[shift:])
+	reverseLEDs(l.leds)
+}
+
+func reverseLEDs(leds []uint32) {
+	for i, j := 0, len(leds)-1; i < j; i, j = i+1, j-1 {
+		leds[i], leds[j] = leds[j], leds[i]
+	}
+}
+
+//returns c with the format 0xWWRRGGBB
+func colorToUInt32(c color.Color) uint32 {
+	// A color's RGBA method returns values in the range [0, 65535]
+	red, green, blue, alpha := c.RGBA()
+	return (alpha>>8)<<24 | (red>>8)<<16 | (green>>8)<<8 | blue>>8
 }
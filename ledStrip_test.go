@@ -0,0 +1,134 @@
+package rpiws281x
+
+import (
+	"fmt"
+	"image/color"
+	"testing"
+)
+
+//otherLEDs hides the type of a LEDStrip so that the generic path of CopyFrom is used
+type otherLEDs struct {
+	*LEDStrip
+}
+
+//returns a strip with count LEDs set to 1, 2, 3 ...
+func countingStrip(count int) *LEDStrip {
+	res := NewLEDStrip(count)
+	for i := 0; i < count; i++ {
+		res.SetDirect(i, uint32(i+1))
+	}
+	return res
+}
+
+func checkLEDs(t *testing.T, name string, l *LEDStrip, want []uint32) {
+	t.Helper()
+	if l.TotalCount() != len(want) {
+		t.Errorf("%s: got %d LEDs want %d", name, l.TotalCount(), len(want))
+		return
+	}
+	for i := range want {
+		if l.UInt32(i) != want[i] {
+			t.Errorf("%s: LED %d: got %x want %x", name, i, l.UInt32(i), want[i])
+		}
+	}
+}
+
+func TestLEDStripFill(t *testing.T) {
+	l := NewLEDStrip(3)
+	l.Fill(0x01020304)
+	checkLEDs(t, "Fill", l, []uint32{0x01020304, 0x01020304, 0x01020304})
+	l.FillColor(color.RGBA{R: 0x10, G: 0x20, B: 0x30, A: 0xff})
+	checkLEDs(t, "FillColor", l, []uint32{0xff102030, 0xff102030, 0xff102030})
+	empty := NewLEDStrip(0)
+	empty.Fill(1)
+	checkLEDs(t, "Fill of empty strip", empty, nil)
+}
+
+func TestLEDStripCopyFrom(t *testing.T) {
+	tests := []struct {
+		name  string
+		count int
+		src   LEDs
+		want  []uint32
+	}{
+		{"same size", 3, countingStrip(3), []uint32{1, 2, 3}},
+		{"larger source", 2, countingStrip(4), []uint32{1, 2}},
+		{"smaller source", 4, countingStrip(2), []uint32{1, 2, 0, 0}},
+		{"other LEDs", 3, otherLEDs{countingStrip(3)}, []uint32{1, 2, 3}},
+		{"larger other LEDs", 2, otherLEDs{countingStrip(4)}, []uint32{1, 2}},
+		{"smaller other LEDs", 4, otherLEDs{countingStrip(2)}, []uint32{1, 2, 0, 0}},
+	}
+	for _, test := range tests {
+		l := NewLEDStrip(test.count)
+		l.CopyFrom(test.src)
+		checkLEDs(t, test.name, l, test.want)
+	}
+}
+
+func TestLEDStripSlice(t *testing.T) {
+	l := countingStrip(3)
+	leds := l.Slice()
+	if len(leds) != 3 || leds[0] != 1 || leds[2] != 3 {
+		t.Fatalf("got %v", leds)
+	}
+	//No copy
+	leds[1] = 0xff
+	if l.UInt32(1) != 0xff {
+		t.Errorf("change of the slice not applied to the strip")
+	}
+}
+
+func TestLEDStripShiftRight(t *testing.T) {
+	tests := []struct {
+		shift int
+		want  []uint32
+	}{
+		{0, []uint32{1, 2, 3, 4, 5}},
+		{-1, []uint32{1, 2, 3, 4, 5}},
+		{1, []uint32{5, 1, 2, 3, 4}},
+		{3, []uint32{3, 4, 5, 1, 2}},
+		{5, []uint32{1, 2, 3, 4, 5}},
+		{7, []uint32{4, 5, 1, 2, 3}},
+	}
+	for _, test := range tests {
+		l := countingStrip(5)
+		leds := l.Slice()
+		l.ShiftRight(test.shift)
+		checkLEDs(t, fmt.Sprintf("ShiftRight %d", test.shift), l, test.want)
+		//In place
+		if &l.Slice()[0] != &leds[0] {
+			t.Errorf("ShiftRight %d reallocated the LEDs", test.shift)
+		}
+	}
+}
+
+func TestLEDStripShiftLeft(t *testing.T) {
+	tests := []struct {
+		shift int
+		want  []uint32
+	}{
+		{0, []uint32{1, 2, 3, 4, 5}},
+		{-1, []uint32{1, 2, 3, 4, 5}},
+		{1, []uint32{2, 3, 4, 5, 1}},
+		{3, []uint32{4, 5, 1, 2, 3}},
+		{4, []uint32{5, 1, 2, 3, 4}},
+		{5, []uint32{1, 2, 3, 4, 5}},
+		{7, []uint32{3, 4, 5, 1, 2}},
+	}
+	for _, test := range tests {
+		l := countingStrip(5)
+		leds := l.Slice()
+		l.ShiftLeft(test.shift)
+		checkLEDs(t, fmt.Sprintf("ShiftLeft %d", test.shift), l, test.want)
+		if &l.Slice()[0] != &leds[0] {
+			t.Errorf("ShiftLeft %d reallocated the LEDs", test.shift)
+		}
+	}
+}
+
+func TestLEDStripShiftEmpty(t *testing.T) {
+	l := NewLEDStrip(0)
+	l.ShiftLeft(1)
+	l.ShiftRight(1)
+	checkLEDs(t, "shift of empty strip", l, nil)
+}
@@ -0,0 +1,214 @@
+package rpiws281x
+
+import (
+	"image/color"
+
+	"github.com/pkg/errors"
+)
+
+//WritableLEDs are LEDs which colors can be set. LEDStrip implements WritableLEDs.
+type WritableLEDs interface {
+	LEDs
+	SetColor(position int, c color.Color)
+}
+
+//MatrixOrder defines how the LEDs of a panel or the panels of a matrix are chained.
+type MatrixOrder uint8
+
+//Valid MatrixOrders
+const (
+	MatrixRowMajor    MatrixOrder = iota // Chained row by row starting top left
+	MatrixColumnMajor                    // Chained column by column starting top left
+)
+
+//MatrixRotation defines how the matrix is mounted. The rotation is clockwise.
+type MatrixRotation uint8
+
+//Valid MatrixRotations
+const (
+	MatrixRotate0 MatrixRotation = iota
+	MatrixRotate90
+	MatrixRotate180
+	MatrixRotate270
+)
+
+//MatrixLayout describes the wiring of a matrix. Width, Height, Order and Zigzag describe one panel as wired (unrotated).
+//A matrix can be tiled from several identical panels which are chained by TileOrder and TileZigzag.
+type MatrixLayout struct {
+	Width  int         // LEDs per row of one panel
+	Height int         // LEDs per column of one panel
+	Order  MatrixOrder // Wiring order of the LEDs within a panel
+	Zigzag bool        // Every second row (or column) is wired in the opposite direction (serpentine)
+
+	TilesX     int         // Number of panels side by side. 0 is treated as 1
+	TilesY     int         // Number of panels on top of each other. 0 is treated as 1
+	TileOrder  MatrixOrder // Order in which the panels are chained
+	TileZigzag bool        // Every second row (or column) of panels is chained in the opposite direction
+
+	Rotation MatrixRotation // Rotation of the whole matrix
+	FlipX    bool           // Mirror the visible matrix horizontally. Applied after the rotation
+	FlipY    bool           // Mirror the visible matrix vertically. Applied after the rotation
+}
+
+//Matrix maps x and y coordinates to the positions of WritableLEDs. Coordinates start top left with 0, 0.
+//The Matrix itself implements WritableLEDs with the positions of the wrapped LEDs so it can be used with SetStrip.
+type Matrix struct {
+	leds      WritableLEDs
+	width     int   // Visible width after rotation
+	height    int   // Visible height after rotation
+	positions []int // Position of the LED for each coordinate. Index is y*width+x
+}
+
+//NewMatrix returns a Matrix over leds with the given layout. leds need at least as many LEDs as the matrix.
+func NewMatrix(leds WritableLEDs, layout MatrixLayout) (*Matrix, error) {
+	if layout.TilesX == 0 {
+		layout.TilesX = 1
+	}
+	if layout.TilesY == 0 {
+		layout.TilesY = 1
+	}
+	if layout.Width <= 0 || layout.Height <= 0 || layout.TilesX < 0 || layout.TilesY < 0 {
+		return nil, errors.Wrap(ErrWrongMatrixSize, "new matrix")
+	}
+	if layout.Order > MatrixColumnMajor || layout.TileOrder > MatrixColumnMajor {
+		return nil, errors.Wrap(ErrWrongMatrixOrder, "new matrix")
+	}
+	if layout.Rotation > MatrixRotate270 {
+		return nil, errors.Wrap(ErrWrongMatrixRotation, "new matrix")
+	}
+	physWidth := layout.Width * layout.TilesX
+	physHeight := layout.Height * layout.TilesY
+	if physWidth*physHeight > leds.TotalCount() {
+		return nil, errors.Wrap(ErrWrongMatrixSize, "new matrix")
+	}
+	m := &Matrix{
+		leds:      leds,
+		width:     physWidth,
+		height:    physHeight,
+		positions: make([]int, physWidth*physHeight),
+	}
+	if layout.Rotation == MatrixRotate90 || layout.Rotation == MatrixRotate270 {
+		m.width, m.height = physHeight, physWidth
+	}
+	for y := 0; y < m.height; y++ {
+		for x := 0; x < m.width; x++ {
+			m.positions[y*m.width+x] = layout.position(x, y, m.width, m.height)
+		}
+	}
+	return m, nil
+}
+
+//returns the position on the LEDs for the visible coordinate x, y of a matrix with the visible size width, height
+func (layout MatrixLayout) position(x, y, width, height int) int {
+	//Undo the flips of the visible matrix first as they are applied after the rotation
+	if layout.FlipX {
+		x = width - 1 - x
+	}
+	if layout.FlipY {
+		y = height - 1 - y
+	}
+	//Coordinates on the unrotated matrix
+	physX, physY := x, y
+	switch layout.Rotation {
+	case MatrixRotate90:
+		physX, physY = y, width-1-x
+	case MatrixRotate180:
+		physX, physY = width-1-x, height-1-y
+	case MatrixRotate270:
+		physX, physY = height-1-y, x
+	}
+	tile := chainIndex(physX/layout.Width, physY/layout.Height, layout.TilesX, layout.TilesY, layout.TileOrder, layout.TileZigzag)
+	led := chainIndex(physX%layout.Width, physY%layout.Height, layout.Width, layout.Height, layout.Order, layout.Zigzag)
+	return tile*layout.Width*layout.Height + led
+}
+
+//returns the index of x, y in a grid of width and height chained by order
+func chainIndex(x, y, width, height int, order MatrixOrder, zigzag bool) int {
+	if order == MatrixColumnMajor {
+		if zigzag && x%2 == 1 {
+			y = height - 1 - y
+		}
+		return x*height + y
+	}
+	if zigzag && y%2 == 1 {
+		x = width - 1 - x
+	}
+	return y*width + x
+}
+
+//Width returns the visible width of the matrix.
+func (m *Matrix) Width() int {
+	return m.width
+}
+
+//Height returns the visible height of the matrix.
+func (m *Matrix) Height() int {
+	return m.height
+}
+
+//Position returns the position on the wrapped LEDs for x, y. Returns -1 if x, y is outside of the matrix.
+func (m *Matrix) Position(x, y int) int {
+	if x < 0 || x >= m.width || y < 0 || y >= m.height {
+		return -1
+	}
+	return m.positions[y*m.width+x]
+}
+
+//Set sets the LED at x, y to the color from color.Color. Coordinates outside of the matrix are ignored.
+func (m *Matrix) Set(x, y int, c color.Color) {
+	position := m.Position(x, y)
+	if position < 0 {
+		return
+	}
+	m.leds.SetColor(position, c)
+}
+
+//At returns the color of the LED at x, y. The white value is returned as alpha (see SingleLED).
+func (m *Matrix) At(x, y int) color.Color {
+	position := m.Position(x, y)
+	if position < 0 {
+		return color.RGBA{}
+	}
+	led := SingleLED(m.leds.UInt32(position))
+	return led.ToColor()
+}
+
+//LEDs returns the wrapped LEDs.
+func (m *Matrix) LEDs() WritableLEDs {
+	return m.leds
+}
+
+//TotalCount returns the number of wrapped LEDs.
+func (m *Matrix) TotalCount() int {
+	return m.leds.TotalCount()
+}
+
+//Red returns the red color amount at position of the wrapped LEDs.
+func (m *Matrix) Red(position int) uint8 {
+	return m.leds.Red(position)
+}
+
+//Green returns the green color amount at position of the wrapped LEDs.
+func (m *Matrix) Green(position int) uint8 {
+	return m.leds.Green(position)
+}
+
+//Blue returns the blue color amount at position of the wrapped LEDs.
+func (m *Matrix) Blue(position int) uint8 {
+	return m.leds.Blue(position)
+}
+
+//White returns the white color amount at position of the wrapped LEDs.
+func (m *Matrix) White(position int) uint8 {
+	return m.leds.White(position)
+}
+
+//UInt32 returns the color at position of the wrapped LEDs as uint32. Format 0xWWRRGGBB
+func (m *Matrix) UInt32(position int) uint32 {
+	return m.leds.UInt32(position)
+}
+
+//SetColor sets the color from color.Color at position of the wrapped LEDs.
+func (m *Matrix) SetColor(position int, c color.Color) {
+	m.leds.SetColor(position, c)
+}
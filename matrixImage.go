@@ -0,0 +1,60 @@
+package rpiws281x
+
+import (
+	"image"
+	"image/color"
+)
+
+//MatrixImage is a draw.Image painting on the LEDs of a Matrix. It can be used with draw.Draw, image.Decode or font drawers.
+//
+//Other than Matrix.Set and Matrix.At, the alpha value is not mapped to the white LED. The white value of each LED is kept
+//by Set and At always returns opaque colors. Drawing with draw.Over therefore covers the previous colors.
+type MatrixImage struct {
+	matrix *Matrix
+}
+
+//NewMatrixImage returns a MatrixImage over m.
+func NewMatrixImage(m *Matrix) *MatrixImage {
+	return &MatrixImage{
+		matrix: m,
+	}
+}
+
+//Matrix returns the wrapped Matrix.
+func (i *MatrixImage) Matrix() *Matrix {
+	return i.matrix
+}
+
+//ColorModel returns color.RGBAModel.
+func (i *MatrixImage) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+//Bounds returns the visible size of the matrix starting at 0, 0.
+func (i *MatrixImage) Bounds() image.Rectangle {
+	return image.Rect(0, 0, i.matrix.Width(), i.matrix.Height())
+}
+
+//At returns the opaque color of the LED at x, y. Coordinates outside of the matrix return transparent black.
+func (i *MatrixImage) At(x, y int) color.Color {
+	position := i.matrix.Position(x, y)
+	if position < 0 {
+		return color.RGBA{}
+	}
+	leds := i.matrix.LEDs()
+	return color.RGBA{leds.Red(position), leds.Green(position), leds.Blue(position), 0xff}
+}
+
+//Set sets the red, green and blue value of the LED at x, y. Coordinates outside of the matrix are ignored.
+//Colors which are not opaque are applied premultiplied, i.e. as drawn on black.
+func (i *MatrixImage) Set(x, y int, c color.Color) {
+	position := i.matrix.Position(x, y)
+	if position < 0 {
+		return
+	}
+	leds := i.matrix.LEDs()
+	rgba := color.RGBAModel.Convert(c).(color.RGBA)
+	//The alpha value is the white value for SetColor
+	rgba.A = leds.White(position)
+	leds.SetColor(position, rgba)
+}
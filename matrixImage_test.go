@@ -0,0 +1,57 @@
+package rpiws281x
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestMatrixImageDraw(t *testing.T) {
+	strip := NewLEDStrip(6)
+	strip.Fill(0x55000000)
+	//Visible 2x3. Positions by y and x: {5, 0}, {4, 1}, {3, 2}
+	m, err := NewMatrix(strip, MatrixLayout{Width: 3, Height: 2, Zigzag: true, Rotation: MatrixRotate90})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := NewMatrixImage(m)
+	if img.Matrix() != m {
+		t.Error("Matrix: got another matrix")
+	}
+	if img.ColorModel() != color.RGBAModel {
+		t.Error("ColorModel: not color.RGBAModel")
+	}
+	if got := img.Bounds(); got != image.Rect(0, 0, 2, 3) {
+		t.Errorf("Bounds: got %v want %v", got, image.Rect(0, 0, 2, 3))
+	}
+	src := image.NewRGBA(image.Rect(0, 0, 2, 3))
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 2; x++ {
+			src.SetRGBA(x, y, color.RGBA{uint8(x + 1), uint8(y+1) * 0x10, 0x80, 0xff})
+		}
+	}
+	draw.Draw(img, img.Bounds(), src, image.Point{}, draw.Src)
+	//The white value is kept
+	want := []uint32{0x55021080, 0x55022080, 0x55023080, 0x55013080, 0x55012080, 0x55011080}
+	checkLEDs(t, "draw", strip, want)
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 2; x++ {
+			if got := img.At(x, y); got != src.At(x, y) {
+				t.Errorf("At %d, %d: got %v want %v", x, y, got, src.At(x, y))
+			}
+		}
+	}
+	if got := img.At(2, 0); got != (color.RGBA{}) {
+		t.Errorf("At outside: got %v want transparent", got)
+	}
+
+	//Only the LEDs within the rectangle are drawn
+	draw.Draw(img, image.Rect(1, 1, 3, 2), image.NewUniform(color.RGBA{0xff, 0, 0, 0xff}), image.Point{}, draw.Src)
+	want[1] = 0x55ff0000
+	checkLEDs(t, "draw of a rectangle", strip, want)
+	//Not opaque colors are applied as drawn on black
+	img.Set(0, 0, color.NRGBA{0xff, 0, 0, 0x80})
+	want[5] = 0x55800000
+	checkLEDs(t, "set of a transparent color", strip, want)
+}
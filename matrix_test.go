@@ -0,0 +1,111 @@
+package rpiws281x
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestMatrixPositions(t *testing.T) {
+	panel := MatrixLayout{Width: 3, Height: 2}
+	with := func(change func(layout *MatrixLayout)) MatrixLayout {
+		layout := panel
+		change(&layout)
+		return layout
+	}
+	tests := []struct {
+		name   string
+		layout MatrixLayout
+		want   [][]int // Positions by y and x
+	}{
+		{"row major", panel, [][]int{{0, 1, 2}, {3, 4, 5}}},
+		{"row major zigzag", with(func(l *MatrixLayout) { l.Zigzag = true }), [][]int{{0, 1, 2}, {5, 4, 3}}},
+		{"column major", with(func(l *MatrixLayout) { l.Order = MatrixColumnMajor }), [][]int{{0, 2, 4}, {1, 3, 5}}},
+		{"column major zigzag", with(func(l *MatrixLayout) { l.Order, l.Zigzag = MatrixColumnMajor, true }), [][]int{{0, 3, 4}, {1, 2, 5}}},
+		{"rotate 90", with(func(l *MatrixLayout) { l.Rotation = MatrixRotate90 }), [][]int{{3, 0}, {4, 1}, {5, 2}}},
+		{"rotate 180", with(func(l *MatrixLayout) { l.Rotation = MatrixRotate180 }), [][]int{{5, 4, 3}, {2, 1, 0}}},
+		{"rotate 270", with(func(l *MatrixLayout) { l.Rotation = MatrixRotate270 }), [][]int{{2, 5}, {1, 4}, {0, 3}}},
+		{"rotate 90 zigzag", with(func(l *MatrixLayout) { l.Rotation, l.Zigzag = MatrixRotate90, true }), [][]int{{5, 0}, {4, 1}, {3, 2}}},
+		{"flip x", with(func(l *MatrixLayout) { l.FlipX = true }), [][]int{{2, 1, 0}, {5, 4, 3}}},
+		{"flip y", with(func(l *MatrixLayout) { l.FlipY = true }), [][]int{{3, 4, 5}, {0, 1, 2}}},
+		{"flip x and y", with(func(l *MatrixLayout) { l.FlipX, l.FlipY = true, true }), [][]int{{5, 4, 3}, {2, 1, 0}}},
+		//The flips mirror the rotated matrix
+		{"rotate 90 flip x", with(func(l *MatrixLayout) { l.Rotation, l.FlipX = MatrixRotate90, true }), [][]int{{0, 3}, {1, 4}, {2, 5}}},
+		{"rotate 90 flip y", with(func(l *MatrixLayout) { l.Rotation, l.FlipY = MatrixRotate90, true }), [][]int{{5, 2}, {4, 1}, {3, 0}}},
+		{"tiles side by side", MatrixLayout{Width: 2, Height: 2, TilesX: 2},
+			[][]int{{0, 1, 4, 5}, {2, 3, 6, 7}}},
+		{"tiles zigzag", MatrixLayout{Width: 2, Height: 1, TilesX: 2, TilesY: 2, TileZigzag: true},
+			[][]int{{0, 1, 2, 3}, {6, 7, 4, 5}}},
+		{"tiles column major", MatrixLayout{Width: 1, Height: 1, TilesX: 2, TilesY: 2, TileOrder: MatrixColumnMajor},
+			[][]int{{0, 2}, {1, 3}}},
+		{"tiles rotate 180", MatrixLayout{Width: 2, Height: 1, TilesX: 2, Rotation: MatrixRotate180},
+			[][]int{{3, 2, 1, 0}}},
+	}
+	for _, test := range tests {
+		m, err := NewMatrix(NewLEDStrip(8), test.layout)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if m.Height() != len(test.want) || m.Width() != len(test.want[0]) {
+			t.Errorf("%s: got size %dx%d want %dx%d", test.name, m.Width(), m.Height(), len(test.want[0]), len(test.want))
+			continue
+		}
+		for y := range test.want {
+			for x, want := range test.want[y] {
+				if got := m.Position(x, y); got != want {
+					t.Errorf("%s: position of %d, %d: got %d want %d", test.name, x, y, got, want)
+				}
+			}
+		}
+		for _, outside := range [][2]int{{-1, 0}, {0, -1}, {m.Width(), 0}, {0, m.Height()}} {
+			if got := m.Position(outside[0], outside[1]); got != -1 {
+				t.Errorf("%s: position of %d, %d: got %d want -1", test.name, outside[0], outside[1], got)
+			}
+		}
+	}
+}
+
+func TestMatrixErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		layout MatrixLayout
+		want   error
+	}{
+		{"no width", MatrixLayout{Height: 2}, ErrWrongMatrixSize},
+		{"negative tiles", MatrixLayout{Width: 2, Height: 2, TilesX: -1}, ErrWrongMatrixSize},
+		{"too few LEDs", MatrixLayout{Width: 3, Height: 3}, ErrWrongMatrixSize},
+		{"order", MatrixLayout{Width: 2, Height: 2, Order: MatrixColumnMajor + 1}, ErrWrongMatrixOrder},
+		{"tile order", MatrixLayout{Width: 2, Height: 2, TileOrder: MatrixColumnMajor + 1}, ErrWrongMatrixOrder},
+		{"rotation", MatrixLayout{Width: 2, Height: 2, Rotation: MatrixRotate270 + 1}, ErrWrongMatrixRotation},
+	}
+	for _, test := range tests {
+		_, err := NewMatrix(NewLEDStrip(8), test.layout)
+		if errors.Cause(err) != test.want {
+			t.Errorf("%s: got %v want %v", test.name, err, test.want)
+		}
+	}
+}
+
+func TestMatrixSetAt(t *testing.T) {
+	strip := NewLEDStrip(6)
+	m, err := NewMatrix(strip, MatrixLayout{Width: 3, Height: 2, Zigzag: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Set(0, 1, color.RGBA{R: 0x10, G: 0x20, B: 0x30, A: 0x40})
+	if got := strip.UInt32(5); got != 0x40102030 {
+		t.Errorf("LED 5: got %x want %x", got, 0x40102030)
+	}
+	if got := m.At(0, 1); got != (color.RGBA{R: 0x10, G: 0x20, B: 0x30, A: 0x40}) {
+		t.Errorf("At: got %v", got)
+	}
+	//Ignored
+	m.Set(3, 0, color.RGBA{R: 0xff})
+	for i := 0; i < 5; i++ {
+		if strip.UInt32(i) != 0 {
+			t.Errorf("LED %d changed to %x", i, strip.UInt32(i))
+		}
+	}
+}
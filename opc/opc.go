@@ -0,0 +1,52 @@
+//Package opc implements an Open Pixel Control server which writes the received pixels to the strips of a rpiws281x.Config.
+package opc
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/DerLukas15/rpiws281x"
+)
+
+// Errors
+var (
+	ErrWrongChannel = errors.New("channel has to be between 1 and 255")
+	ErrNotWritable  = errors.New("strip is not writable")
+)
+
+const (
+	DefaultAddress = ":7890" // Default TCP address of OPC servers
+
+	headerSize           = 4 // Channel, command and length
+	cmdSetPixels         = 0
+	cmdSystemExclusive   = 0xff
+	systemFadecandy      = 0x0001 // System ID of the color correction message
+	sysexColorCorrection = 0x0001
+	minAcceptRetryDelay  = 5 * time.Millisecond // First delay after a temporary Accept error
+	maxAcceptRetryDelay  = time.Second
+)
+
+//colorCorrection is the JSON of the Fadecandy color correction message
+type colorCorrection struct {
+	Gamma      float64   `json:"gamma"`
+	Whitepoint []float64 `json:"whitepoint"`
+}
+
+//parses the color correction message. ok is false if the JSON is not valid
+func parseColorCorrection(data []byte) (gamma float64, correction rpiws281x.ColorCorrection, ok bool) {
+	var res colorCorrection
+	if json.Unmarshal(data, &res) != nil {
+		return 0, correction, false
+	}
+	correction = rpiws281x.CorrectionNone
+	factors := []*uint8{&correction.Red, &correction.Green, &correction.Blue}
+	for i, curFactor := range res.Whitepoint {
+		if i >= len(factors) {
+			break
+		}
+		*factors[i] = uint8(math.Round(math.Max(0, math.Min(1, curFactor)) * 255))
+	}
+	return res.Gamma, correction, true
+}
@@ -0,0 +1,225 @@
+package opc
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/DerLukas15/rpiws281x"
+	"github.com/DerLukas15/rpiws281x/internal/receive"
+	"github.com/pkg/errors"
+)
+
+//a strip written by an OPC channel
+type channel struct {
+	stripIndex int
+	leds       rpiws281x.WritableLEDs
+	colorCount int
+}
+
+//Server writes the pixels of OPC "set pixel colours" messages to the strips of a Config and renders them.
+/*
+Channel 0 writes the pixels to all mapped strips. Every other channel writes to the strip it is mapped to. By default
+channel n is mapped to the strip with index n-1. The pixels start at the first LED of the strip. The white value of RGBW strips is set to 0.
+
+The system exclusive color correction message of the Fadecandy sets the gamma and the white point as color correction of all
+mapped strips. linearSlope and linearCutoff are ignored.
+
+Several clients can be connected at the same time. Their messages are handled one after the other.
+*/
+type Server struct {
+	config   *rpiws281x.Config
+	mu       sync.Mutex // Protects the strips and Render
+	channels map[uint8]channel
+}
+
+//NewServer returns a Server for the strips of config. Channel n is mapped to the strip with index n-1 for all strips set
+//with SetStrip before.
+func NewServer(config *rpiws281x.Config) *Server {
+	s := &Server{
+		config:   config,
+		channels: make(map[uint8]channel),
+	}
+	for stripIndex := 0; stripIndex < 0xff; stripIndex++ {
+		err := s.SetChannel(uint8(stripIndex+1), stripIndex)
+		if errors.Cause(err) == rpiws281x.ErrConfigWrongIndex {
+			break
+		}
+	}
+	return s
+}
+
+//SetChannel maps the OPC channel to the strip with index stripIndex. The strip has to be set with SetStrip before.
+func (s *Server) SetChannel(opcChannel uint8, stripIndex int) error {
+	if opcChannel == 0 {
+		return errors.Wrap(ErrWrongChannel, "server SetChannel")
+	}
+	strip, stripType, err := s.config.Strip(stripIndex)
+	if err != nil {
+		return errors.Wrap(err, "server SetChannel")
+	}
+	leds, ok := strip.(rpiws281x.WritableLEDs)
+	if !ok {
+		return errors.Wrap(ErrNotWritable, "server SetChannel")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.channels[opcChannel] = channel{
+		stripIndex: stripIndex,
+		leds:       leds,
+		colorCount: stripType.ColorCount(),
+	}
+	return nil
+}
+
+//Handle processes one OPC message. Unknown commands and channels are ignored. An error is only returned if Render fails.
+func (s *Server) Handle(opcChannel, command uint8, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch command {
+	case cmdSetPixels:
+		return s.setPixels(opcChannel, data)
+	case cmdSystemExclusive:
+		if len(data) < 4 || binary.BigEndian.Uint16(data) != systemFadecandy ||
+			binary.BigEndian.Uint16(data[2:]) != sysexColorCorrection {
+			return nil
+		}
+		gamma, correction, ok := parseColorCorrection(data[4:])
+		if !ok {
+			return nil
+		}
+		for _, curChannel := range s.channels {
+			if gamma > 0 {
+				s.config.SetGamma(gamma, curChannel.stripIndex)
+			}
+			s.config.SetColorCorrection(correction, curChannel.stripIndex)
+		}
+	}
+	return nil
+}
+
+//writes the pixels to the strips of opcChannel and renders them
+func (s *Server) setPixels(opcChannel uint8, data []byte) error {
+	var set receive.RenderSet
+	for curNumber, curChannel := range s.channels {
+		if opcChannel != 0 && opcChannel != curNumber {
+			continue
+		}
+		//OPC has no white value
+		rpiws281x.SetSlots(curChannel.leds, 0, data, 3)
+		set.Add(curChannel.stripIndex)
+	}
+	err := set.Render(s.config)
+	if err != nil {
+		return errors.Wrap(err, "server render")
+	}
+	return nil
+}
+
+//Serve accepts clients on l and handles their messages until ctx is done or Render fails. l and all client connections
+//are closed once Serve returns. A done ctx is not an error.
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	renderErr := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		//Unblock Accept
+		l.Close()
+	}()
+	var err error
+	var retryDelay time.Duration
+	for {
+		var conn net.Conn
+		conn, err = l.Accept()
+		if netErr, ok := err.(net.Error); ok && netErr.Temporary() && ctx.Err() == nil {
+			//Retry with backoff like net/http
+			if retryDelay == 0 {
+				retryDelay = minAcceptRetryDelay
+			} else if retryDelay *= 2; retryDelay > maxAcceptRetryDelay {
+				retryDelay = maxAcceptRetryDelay
+			}
+			select {
+			case <-time.After(retryDelay):
+			case <-ctx.Done():
+			}
+			continue
+		}
+		if err != nil {
+			break
+		}
+		retryDelay = 0
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := s.serveConn(ctx, conn)
+			if err != nil {
+				select {
+				case renderErr <- err:
+				default:
+				}
+				cancel()
+			}
+		}()
+	}
+	done := ctx.Err() != nil
+	//Stop the clients before waiting for them
+	cancel()
+	wg.Wait()
+	select {
+	case err = <-renderErr:
+		return errors.Wrap(err, "server serve")
+	default:
+	}
+	if done {
+		return nil
+	}
+	return errors.Wrap(err, "server serve")
+}
+
+//handles the messages of a client until it disconnects or ctx is done. Only Render errors are returned.
+func (s *Server) serveConn(ctx context.Context, conn net.Conn) error {
+	defer conn.Close()
+	stop := receive.UnblockReads(ctx, conn)
+	defer stop()
+	r := bufio.NewReader(conn)
+	header := make([]byte, headerSize)
+	var data []byte
+	for {
+		_, err := io.ReadFull(r, header)
+		if err != nil {
+			return nil
+		}
+		length := int(binary.BigEndian.Uint16(header[2:]))
+		if cap(data) < length {
+			data = make([]byte, length)
+		}
+		data = data[:length]
+		_, err = io.ReadFull(r, data)
+		if err != nil {
+			return nil
+		}
+		err = s.Handle(header[0], header[1], data)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+//ListenAndServe listens on the TCP address addr and handles the clients until ctx is done or Render fails. DefaultAddress
+//is used if addr is empty. A done ctx is not an error.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	if addr == "" {
+		addr = DefaultAddress
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return errors.Wrap(err, "server listen")
+	}
+	return s.Serve(ctx, l)
+}
@@ -0,0 +1,238 @@
+package opc
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DerLukas15/rpiws281x"
+	"github.com/DerLukas15/rpiws281x/internal/receivetest"
+)
+
+//returns an OPC message
+func message(opcChannel, command uint8, data []byte) []byte {
+	res := []byte{opcChannel, command, 0, 0}
+	binary.BigEndian.PutUint16(res[2:], uint16(len(data)))
+	return append(res, data...)
+}
+
+func TestHandleSetPixels(t *testing.T) {
+	setup := receivetest.NewSetup(t)
+	s := NewServer(setup.Config)
+	u := receivetest.Unrendered
+	tests := []struct {
+		opcChannel uint8
+		data       []byte
+		want       [][]uint32 // nil if no frame is rendered
+	}{
+		{1, []byte{1, 2, 3, 4, 5, 6}, [][]uint32{{0x010203, 0x040506, 0, 0, 0}, {u, u, u}}},
+		{2, []byte{7, 8, 9}, [][]uint32{{u, u, u, u, u}, {0x070809, 0, 0}}},
+		//Channel 0 writes to all strips
+		{0, []byte{10, 11, 12}, [][]uint32{{0x0a0b0c, 0x040506, 0, 0, 0}, {0x0a0b0c, 0, 0}}},
+		//More pixels than LEDs
+		{2, bytes.Repeat([]byte{0xff}, 5*3), [][]uint32{{u, u, u, u, u}, {0xffffff, 0xffffff, 0xffffff}}},
+		//Unmapped channel
+		{9, []byte{1, 2, 3}, nil},
+	}
+	var want [][][]uint32
+	for i, test := range tests {
+		err := s.Handle(test.opcChannel, cmdSetPixels, test.data)
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if test.want != nil {
+			want = append(want, test.want)
+		}
+	}
+	setup.CheckFrames(t, want)
+}
+
+func TestHandleColorCorrection(t *testing.T) {
+	setup := receivetest.NewSetup(t)
+	s := NewServer(setup.Config)
+	sysex := []byte{0, systemFadecandy, 0, sysexColorCorrection}
+	err := s.Handle(0, cmdSystemExclusive, append(sysex, `{"gamma": 2.5, "whitepoint": [0.5, 1.0, 1.5]}`...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	//Other systems and invalid JSON are ignored
+	err = s.Handle(0, cmdSystemExclusive, append([]byte{0, 2, 0, 1}, `{"gamma": 1}`...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Handle(0, cmdSystemExclusive, append(sysex, `{"gamma":`...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frames := setup.Frames(t); len(frames) != 0 {
+		t.Errorf("got %d frames for color correction", len(frames))
+	}
+	gamma, correction, ok := parseColorCorrection([]byte(`{"gamma": 2.5, "whitepoint": [0.5, 1.0, 1.5]}`))
+	if !ok || gamma != 2.5 {
+		t.Fatalf("got gamma %v ok %v", gamma, ok)
+	}
+	want := rpiws281x.ColorCorrection{Red: 128, Green: 255, Blue: 255, White: rpiws281x.CorrectionNone.White}
+	if correction != want {
+		t.Errorf("got %+v want %+v", correction, want)
+	}
+}
+
+//testListener returns the errors of accept before it blocks until it is closed
+type testListener struct {
+	mu      sync.Mutex
+	accepts []func() (net.Conn, error)
+	calls   int
+	closed  chan struct{}
+	once    sync.Once
+}
+
+func newTestListener(accepts ...func() (net.Conn, error)) *testListener {
+	return &testListener{
+		accepts: accepts,
+		closed:  make(chan struct{}),
+	}
+}
+
+func (l *testListener) Accept() (net.Conn, error) {
+	l.mu.Lock()
+	l.calls++
+	var accept func() (net.Conn, error)
+	if len(l.accepts) > 0 {
+		accept = l.accepts[0]
+		l.accepts = l.accepts[1:]
+	}
+	l.mu.Unlock()
+	if accept != nil {
+		return accept()
+	}
+	<-l.closed
+	return nil, net.ErrClosed
+}
+
+func (l *testListener) Close() error {
+	l.once.Do(func() { close(l.closed) })
+	return nil
+}
+
+func (l *testListener) Addr() net.Addr {
+	return &net.TCPAddr{}
+}
+
+func (l *testListener) acceptCalls() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.calls
+}
+
+//temporaryError is a temporary net.Error like EMFILE
+type temporaryError struct{}
+
+func (temporaryError) Error() string   { return "temporary" }
+func (temporaryError) Timeout() bool   { return false }
+func (temporaryError) Temporary() bool { return true }
+
+func TestServe(t *testing.T) {
+	setup := receivetest.NewSetup(t)
+	s := NewServer(setup.Config)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(ctx, l)
+	}()
+	var clients []net.Conn
+	for i := 0; i < 2; i++ {
+		client, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer client.Close()
+		clients = append(clients, client)
+	}
+	//A message split over several writes
+	msg := message(1, cmdSetPixels, []byte{1, 2, 3})
+	clients[0].Write(msg[:2])
+	time.Sleep(10 * time.Millisecond)
+	clients[0].Write(msg[2:])
+	clients[1].Write(message(2, cmdSetPixels, []byte{4, 5, 6}))
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case err = <-served:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Serve did not return")
+	}
+	//The clients are disconnected
+	clients[0].SetReadDeadline(time.Now().Add(time.Second))
+	_, err = clients[0].Read(make([]byte, 1))
+	if err != io.EOF {
+		t.Errorf("got %v want EOF", err)
+	}
+	if frames := setup.Frames(t); len(frames) != 2 {
+		t.Errorf("got %d frames want 2", len(frames))
+	}
+}
+
+func TestServeTemporaryAcceptError(t *testing.T) {
+	s := NewServer(receivetest.NewSetup(t).Config)
+	temporary := func() (net.Conn, error) { return nil, temporaryError{} }
+	l := newTestListener(temporary, temporary, temporary)
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(ctx, l)
+	}()
+	deadline := time.Now().Add(time.Second)
+	for l.acceptCalls() < 4 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if l.acceptCalls() < 4 {
+		t.Fatalf("Accept called %d times", l.acceptCalls())
+	}
+	cancel()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Serve did not return")
+	}
+}
+
+func TestServeAcceptError(t *testing.T) {
+	s := NewServer(receivetest.NewSetup(t).Config)
+	server, client := net.Pipe()
+	defer client.Close()
+	acceptErr := errors.New("accept failed")
+	l := newTestListener(
+		func() (net.Conn, error) { return server, nil },
+		func() (net.Conn, error) { return nil, acceptErr },
+	)
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(context.Background(), l)
+	}()
+	//The connected client must not keep Serve running
+	select {
+	case err := <-served:
+		if err == nil || !errors.Is(err, acceptErr) {
+			t.Fatalf("got %v want %v", err, acceptErr)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Serve did not return")
+	}
+}
@@ -0,0 +1,249 @@
+package rpiws281x
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/DerLukas15/rpigpio"
+	"github.com/DerLukas15/rpihardware"
+	"github.com/DerLukas15/rpimemmap"
+	"github.com/pkg/errors"
+)
+
+/*
+ * Pin map of alternate pin configuration for PCM (PCM_DOUT)
+ * GPIO    PCM
+ *  21      0
+ *  31      2
+ */
+
+const (
+	registerPCMBusOffset uint32 = 0x00203000
+
+	//Register Offsets
+	registerOffsetPCMCs    uint32 = 0x00 // Control and status
+	registerOffsetPCMFifo  uint32 = 0x04 // FIFO data
+	registerOffsetPCMMode  uint32 = 0x08 // Mode
+	registerOffsetPCMRxc   uint32 = 0x0c // Receive configuration
+	registerOffsetPCMTxc   uint32 = 0x10 // Transmit configuration
+	registerOffsetPCMDreq  uint32 = 0x14 // DMA request level
+	registerOffsetPCMInten uint32 = 0x18 // Interrupt enables
+	registerOffsetPCMIntst uint32 = 0x1c // Interrupt status
+	registerOffsetPCMGray  uint32 = 0x20 // Gray mode control
+
+	//PCM register values
+	registerValuePCMCsEn     uint32 = (1 << 0)  // Enable PCM
+	registerValuePCMCsRxon   uint32 = (1 << 1)  // Enable reception
+	registerValuePCMCsTxon   uint32 = (1 << 2)  // Enable transmission
+	registerValuePCMCsTxclr  uint32 = (1 << 3)  // Clear TX FIFO
+	registerValuePCMCsRxclr  uint32 = (1 << 4)  // Clear RX FIFO
+	registerValuePCMCsDmaen  uint32 = (1 << 9)  // Enable DMA DREQ
+	registerValuePCMCsTxsync uint32 = (1 << 13) // TX FIFO is in sync
+	registerValuePCMCsTxerr  uint32 = (1 << 15) // TX FIFO underrun
+	registerValuePCMCsTxw    uint32 = (1 << 17) // TX FIFO needs writing
+	registerValuePCMCsTxd    uint32 = (1 << 19) // TX FIFO can accept data
+	registerValuePCMCsTxe    uint32 = (1 << 21) // TX FIFO is empty
+	registerValuePCMCsSync   uint32 = (1 << 24) // PCM clock sync helper
+	registerValuePCMCsStby   uint32 = (1 << 25) // RAM standby disabled
+
+	registerValuePCMTxcCh1en  uint32 = (1 << 30) // Chan 1: enable
+	registerValuePCMTxcCh1wex uint32 = (1 << 31) // Chan 1: width extension
+)
+
+var (
+	registerValuePCMModeFslen = func(val uint32) uint32 { return ((val & 0x3ff) << 0) }  // Frame sync length
+	registerValuePCMModeFlen  = func(val uint32) uint32 { return ((val & 0x3ff) << 10) } // Frame length
+
+	registerValuePCMTxcCh1wid = func(val uint32) uint32 { return ((val & 0xf) << 16) }   // Chan 1: width
+	registerValuePCMTxcCh1pos = func(val uint32) uint32 { return ((val & 0x3ff) << 20) } // Chan 1: position
+
+	registerValuePCMDreqTx      = func(val uint32) uint32 { return ((val & 0x7f) << 8) }  // TX request level
+	registerValuePCMDreqTxPanic = func(val uint32) uint32 { return ((val & 0x7f) << 24) } // TX panic level
+)
+
+var pcmRegisterMem Memory
+var pcmDataMem Memory
+
+var (
+	pin21, _    = rpigpio.NewPin(21)
+	pin31, _    = rpigpio.NewPin(31)
+	pcmChannels = channelPinTable{
+		pinTable{ // Mapping of Pin to alternate function for PCM
+			{
+				pinNum:  pin21,
+				altMode: rpigpio.ModeAlternate0,
+			},
+			{
+				pinNum:  pin31,
+				altMode: rpigpio.ModeAlternate2,
+			},
+		},
+	}
+)
+
+//initializes pcm specific stuff like clock, pcm data, pcm device, dma cb
+func initializePCM(channels []ledChannel, timing *symbolTiming) error {
+	var err error
+	logOutput("Initializing clock peripheral")
+	err = initializeClock()
+	if err != nil {
+		return errors.Wrap(err, "PCM init")
+	}
+	logOutput("Done clock")
+
+	if pcmRegisterMem == nil {
+		logOutput("Initializing PCM")
+		pcmRegisterMem, err = activeBackend.MapPeripheral(registerPCMBusOffset, uint32(os.Getpagesize()))
+		if err != nil {
+			return err
+		}
+		logOutput("Done pcm")
+		logOutput("PCM: " + pcmRegisterMem.String())
+	}
+	pcmRegisterMem.Write32(registerOffsetPCMCs, 0) // Disable PCM before changing the clock
+	time.Sleep(10 * time.Microsecond)
+	logOutput("Setup PCM clock")
+	err = clockSetupPcm(timing.divisor)
+	if err != nil {
+		return err
+	}
+	logOutput("Done PCM clock")
+
+	//One frame is one 32 bit word on channel 1
+	pcmRegisterMem.Write32(registerOffsetPCMMode, registerValuePCMModeFlen(31)|registerValuePCMModeFslen(1))
+	pcmRegisterMem.Write32(registerOffsetPCMTxc, registerValuePCMTxcCh1wex|registerValuePCMTxcCh1en|registerValuePCMTxcCh1pos(0)|registerValuePCMTxcCh1wid(8))
+	pcmRegisterMem.Write32(registerOffsetPCMCs, pcmRegisterMem.Read32(registerOffsetPCMCs)|registerValuePCMCsStby)
+	time.Sleep(10 * time.Microsecond)
+	pcmRegisterMem.Write32(registerOffsetPCMCs, pcmRegisterMem.Read32(registerOffsetPCMCs)|registerValuePCMCsTxclr) // Clear Fifo
+	time.Sleep(10 * time.Microsecond)
+	pcmRegisterMem.Write32(registerOffsetPCMCs, pcmRegisterMem.Read32(registerOffsetPCMCs)|registerValuePCMCsEn)
+	time.Sleep(10 * time.Microsecond)
+	pcmRegisterMem.Write32(registerOffsetPCMCs, pcmRegisterMem.Read32(registerOffsetPCMCs)|registerValuePCMCsDmaen)
+	pcmRegisterMem.Write32(registerOffsetPCMDreq, registerValuePCMDreqTx(0x3f)|registerValuePCMDreqTxPanic(0x10))
+	time.Sleep(10 * time.Microsecond)
+
+	if pcmDataMem != nil {
+		logOutput("Clearing PCM data storage")
+		err = pcmDataMem.Unmap()
+		if err != nil {
+			return err
+		}
+		logOutput("Done clearing")
+	}
+
+	curChannel := &channels[0]
+	ledBitCount := curChannel.strip.TotalCount() * ledColorCount(curChannel.stripType) * 8 * timing.format.Bits // Each LED has 8 Bit per Color which are each mapped to a symbol
+	dataSize := (uint32(ledBitCount>>3) & ^uint32(0x7)) + 8
+	dataSize += 32 //Spacing so that leds render. Same as for PWM
+	logOutput(fmt.Sprintf("Initializing PCM data storage. size: %d bytes", dataSize))
+	allocationFlags := rpimemmap.UncachedMemFlagDirect
+	if curHardware.RPiType == rpihardware.RPiType1 {
+		allocationFlags = 0xc
+	}
+	pcmDataMem, err = activeBackend.MapUncached(dataSize, allocationFlags)
+	if err != nil {
+		return err
+	}
+	//Inversion is done by software for PCM. The idle line has to be high in this case.
+	var idle uint32
+	if curChannel.invert {
+		idle = 0xffffffff
+	}
+	for i := uint32(0); i < dataSize/4; i++ {
+		pcmDataMem.Write32(i*4, idle)
+	}
+	logOutput("Done PCM data storage")
+	logOutput("PCM storage: " + pcmDataMem.String())
+
+	//Initialize dma control block for pcm
+	logOutput("Initializing DmaCB")
+	err = initializeDmaCBPCM(dataSize)
+	if err != nil {
+		return err
+	}
+	logOutput("Done DmaCB")
+
+	pcmRegisterMem.Write32(registerOffsetPCMCs, pcmRegisterMem.Read32(registerOffsetPCMCs)|registerValuePCMCsTxon) // Start transmission
+	if Debug {
+		logOutput(statusPCM())
+	}
+	return nil
+}
+
+//stops pcm and deallocates memory
+func cleanupPCM() error {
+	err := stopPCM()
+	if err != nil {
+		return errors.Wrap(err, "cleanup pcm")
+	}
+	if pcmRegisterMem != nil {
+		err := pcmRegisterMem.Unmap()
+		if err != nil {
+			return errors.Wrap(err, "cleanup pcm")
+		}
+		pcmRegisterMem = nil
+	}
+	if pcmDataMem != nil {
+		err := pcmDataMem.Unmap()
+		if err != nil {
+			return errors.Wrap(err, "cleanup pcm data")
+		}
+		pcmDataMem = nil
+	}
+	err = cleanupDmaCBPCM()
+	if err != nil {
+		return errors.Wrap(err, "cleanup pcm dma cb")
+	}
+	return nil
+}
+
+func stopPCM() error {
+	if pcmRegisterMem == nil {
+		return nil
+	}
+	pcmRegisterMem.Write32(registerOffsetPCMCs, 0)
+	//Stop PCM clock
+	err := stopClockPCM()
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+//prints status of pcm device
+func statusPCM() string {
+	if pcmRegisterMem == nil {
+		return ""
+	}
+	var res string
+	res += fmt.Sprintf("PCM Status:\n")
+	res += fmt.Sprintf("\tEnabled: %t\n", (pcmRegisterMem.Read32(registerOffsetPCMCs)&registerValuePCMCsEn != 0))
+	res += fmt.Sprintf("\tTransmitting: %t\n", (pcmRegisterMem.Read32(registerOffsetPCMCs)&registerValuePCMCsTxon != 0))
+	res += fmt.Sprintf("\tDMA: %t\n", (pcmRegisterMem.Read32(registerOffsetPCMCs)&registerValuePCMCsDmaen != 0))
+	res += fmt.Sprintf("\tFifo empty: %t\n", (pcmRegisterMem.Read32(registerOffsetPCMCs)&registerValuePCMCsTxe != 0))
+	res += fmt.Sprintf("\tFifo underrun: %t\n", (pcmRegisterMem.Read32(registerOffsetPCMCs)&registerValuePCMCsTxerr != 0))
+	return res
+}
+
+//outputs signals with PCM for the given channels
+func renderPCM(channels []ledChannel, timing *symbolTiming) (int64, error) {
+	curChannel := &channels[0]
+	if !curChannel.active {
+		return 0, nil
+	}
+	// PCM has no hardware inversion
+	encodeChannel(curChannel, timing, curChannel.invert)
+	return writePCM(channels, timing), nil
+}
+
+//copies the encoded data of the channel into the data buffer. Returns the time in microseconds needed to send it.
+func writePCM(channels []ledChannel, timing *symbolTiming) int64 {
+	curChannel := &channels[0]
+	if !curChannel.active {
+		return 0
+	}
+	protocolTime := channelProtocolTime(*curChannel, timing)
+	writeWords(pcmDataMem, curChannel.encoded, 0, 1)
+	return int64(protocolTime + timing.reset)
+}
@@ -0,0 +1,80 @@
+package rpiws281x
+
+import (
+	"testing"
+
+	"github.com/DerLukas15/rpigpio"
+)
+
+func TestPCMInitializeRenderStop(t *testing.T) {
+	backend := NewSimulatedBackend()
+	err := SetBackend(backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := New(DriverPCM)
+	strip := testStrip(5, false)
+	//PCM inverts by software
+	err = c.SetStrip(strip, 21, WS2812Strip, 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetBrightness(255, 0)
+	err = c.Initialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+	regs := pcmRegisterMem
+	clock := clockRegisterMem
+	csBits := registerValuePCMCsEn | registerValuePCMCsTxon | registerValuePCMCsDmaen | registerValuePCMCsStby
+	checkRegister(t, "CS", regs, registerOffsetPCMCs, csBits, csBits)
+	checkRegister(t, "MODE", regs, registerOffsetPCMMode, 0xffffffff,
+		registerValuePCMModeFlen(31)|registerValuePCMModeFslen(1))
+	checkRegister(t, "TXC", regs, registerOffsetPCMTxc, 0xffffffff,
+		registerValuePCMTxcCh1wex|registerValuePCMTxcCh1en|registerValuePCMTxcCh1pos(0)|registerValuePCMTxcCh1wid(8))
+	checkRegister(t, "DREQ", regs, registerOffsetPCMDreq, 0xffffffff,
+		registerValuePCMDreqTx(0x3f)|registerValuePCMDreqTxPanic(0x10))
+	checkRegister(t, "clock CTL", clock, registerOffsetClkPcmCtl, registerValueClkCtlEnab|registerValueClkCtlBusy,
+		registerValueClkCtlEnab|registerValueClkCtlBusy)
+	checkRegister(t, "clock DIV", clock, registerOffsetClkPcmDiv, registerValueClkDivDivi(0xfff),
+		registerValueClkDivDivi(c.symbols.divisor))
+	checkPin(t, backend, 21, rpigpio.ModeAlternate0, 0)
+
+	err = c.Render(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transfer := backend.LastTransfer(c.dmaChannel)
+	fifo := backend.PCMFIFO()
+	if len(fifo) != len(transfer) {
+		t.Fatalf("FIFO has %d words, DMA transferred %d", len(fifo), len(transfer))
+	}
+	for i := range fifo {
+		if fifo[i] != transfer[i] {
+			t.Fatalf("FIFO word %d: got %x want %x", i, fifo[i], transfer[i])
+		}
+	}
+	//The idle line is high after the data
+	if transfer[len(transfer)-1] != 0xffffffff {
+		t.Errorf("last word %x is not idle", transfer[len(transfer)-1])
+	}
+	decoded, err := DecodePWM(transfer, []PWMChannelLayout{{
+		LEDCount:  strip.TotalCount(),
+		StripType: WS2812Strip,
+		Invert:    true,
+		Symbols:   c.symbols.format,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDecoded(t, decoded, strip)
+
+	err = c.Stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRegister(t, "CS after Stop", regs, registerOffsetPCMCs, 0xffffffff, 0)
+	checkRegister(t, "clock CTL after Stop", clock, registerOffsetClkPcmCtl, registerValueClkCtlBusy, 0)
+	checkPin(t, backend, 21, rpigpio.ModeOut, 0)
+}
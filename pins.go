@@ -0,0 +1,35 @@
+package rpiws281x
+
+import (
+	"github.com/DerLukas15/rpigpio"
+	"github.com/pkg/errors"
+)
+
+//pinDefinition maps a pin to the alternate function needed by a driver
+type pinDefinition struct {
+	pinNum  *rpigpio.Pin
+	altMode rpigpio.Mode
+}
+
+//pinTable contains all pins usable for one channel of a driver
+type pinTable []pinDefinition
+
+//channelPinTable contains a pinTable per channel of a driver
+type channelPinTable []pinTable
+
+func (pt pinDefinition) getAltMode() (rpigpio.Mode, error) {
+	return pt.altMode, nil
+}
+
+func (ptl pinTable) getAltMode(pinNum uint32) (rpigpio.Mode, error) {
+	for _, curEntry := range ptl {
+		if curEntry.pinNum.Is(pinNum) {
+			return curEntry.getAltMode()
+		}
+	}
+	return 0, errors.Wrap(ErrPinNotAllowed, "")
+}
+
+func (pc channelPinTable) getAltMode(chanNum int, pinNum uint32) (rpigpio.Mode, error) {
+	return pc[chanNum].getAltMode(pinNum)
+}
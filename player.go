@@ -0,0 +1,237 @@
+package rpiws281x
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const recordFrameHeaderSize = 9 // Timestamp and channel mask
+
+//Player plays a recording written by a Recorder.
+type Player struct {
+	r        io.ReadSeeker
+	channels []RecordedChannel
+	frames   []recordedFrame
+	next     int     // Index of the next frame to play
+	speed    float64 // Playback speed. 1 is the original speed
+	loop     bool
+	buf      []byte
+}
+
+//position of a frame in the recording
+type recordedFrame struct {
+	timestamp time.Duration
+	mask      uint8
+	offset    int64 // Offset of the colors
+}
+
+//NewPlayer reads the header of the recording in r and indexes all frames.
+func NewPlayer(r io.ReadSeeker) (*Player, error) {
+	p := &Player{
+		r:     r,
+		speed: 1,
+	}
+	var err error
+	p.channels, err = readRecordHeader(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "new player")
+	}
+	offset, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, errors.Wrap(err, "new player")
+	}
+	header := make([]byte, recordFrameHeaderSize)
+	for {
+		_, err = io.ReadFull(r, header)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(ErrRecordFormat, "new player: incomplete frame")
+		}
+		curFrame := recordedFrame{
+			timestamp: time.Duration(binary.LittleEndian.Uint64(header)),
+			mask:      header[8],
+			offset:    offset + recordFrameHeaderSize,
+		}
+		size := p.frameSize(curFrame.mask)
+		offset, err = r.Seek(int64(size), io.SeekCurrent)
+		if err != nil {
+			return nil, errors.Wrap(err, "new player")
+		}
+		p.frames = append(p.frames, curFrame)
+	}
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, errors.Wrap(err, "new player")
+	}
+	if end != offset {
+		return nil, errors.Wrap(ErrRecordFormat, "new player: incomplete frame")
+	}
+	return p, nil
+}
+
+//returns the size of the colors of a frame with mask
+func (p *Player) frameSize(mask uint8) int {
+	var size int
+	for curChanID, curChannel := range p.channels {
+		if mask&(1<<curChanID) != 0 {
+			size += curChannel.LEDCount * 4
+		}
+	}
+	return size
+}
+
+//Channels returns the recorded channels. The index is the stripIndex used during recording.
+func (p *Player) Channels() []RecordedChannel {
+	return p.channels
+}
+
+//FrameCount returns the number of frames in the recording.
+func (p *Player) FrameCount() int {
+	return len(p.frames)
+}
+
+//Duration returns the time of the last frame.
+func (p *Player) Duration() time.Duration {
+	if len(p.frames) == 0 {
+		return 0
+	}
+	return p.frames[len(p.frames)-1].timestamp
+}
+
+//SetSpeed sets the playback speed. 1 is the original speed, 2 twice as fast.
+func (p *Player) SetSpeed(speed float64) error {
+	if speed <= 0 {
+		return errors.Wrap(ErrWrongSpeed, "player SetSpeed")
+	}
+	p.speed = speed
+	return nil
+}
+
+//SetLoop sets if Play starts again with the first frame after the last one.
+func (p *Player) SetLoop(loop bool) {
+	p.loop = loop
+}
+
+//Seek sets the next frame to play to the last frame at or before position.
+func (p *Player) Seek(position time.Duration) {
+	p.next = 0
+	for i, curFrame := range p.frames {
+		if curFrame.timestamp > position {
+			break
+		}
+		p.next = i
+	}
+}
+
+//SeekFrame sets the next frame to play.
+func (p *Player) SeekFrame(frame int) error {
+	if frame < 0 || frame >= len(p.frames) {
+		return errors.Wrap(ErrConfigWrongIndex, "player SeekFrame")
+	}
+	p.next = frame
+	return nil
+}
+
+//ReadFrame writes the colors of frame to strips. strips[n] receives channel n. Channels without a strip are skipped.
+//Only channels which were rendered in this frame are changed.
+func (p *Player) ReadFrame(frame int, strips []WritableLEDs) error {
+	if frame < 0 || frame >= len(p.frames) {
+		return errors.Wrap(ErrConfigWrongIndex, "player ReadFrame")
+	}
+	curFrame := p.frames[frame]
+	size := p.frameSize(curFrame.mask)
+	if cap(p.buf) < size {
+		p.buf = make([]byte, size)
+	}
+	data := p.buf[:size]
+	_, err := p.r.Seek(curFrame.offset, io.SeekStart)
+	if err != nil {
+		return errors.Wrap(err, "player ReadFrame")
+	}
+	_, err = io.ReadFull(p.r, data)
+	if err != nil {
+		return errors.Wrap(err, "player ReadFrame")
+	}
+	for curChanID, curChannel := range p.channels {
+		if curFrame.mask&(1<<curChanID) == 0 {
+			continue
+		}
+		colors := data[:curChannel.LEDCount*4]
+		data = data[curChannel.LEDCount*4:]
+		if curChanID >= len(strips) || strips[curChanID] == nil {
+			continue
+		}
+		setRecordedColors(strips[curChanID], colors)
+	}
+	return nil
+}
+
+//writes the little endian colors to strip
+func setRecordedColors(strip WritableLEDs, colors []byte) {
+	count := len(colors) / 4
+	if count > strip.TotalCount() {
+		count = strip.TotalCount()
+	}
+	if ledStrip, ok := strip.(*LEDStrip); ok {
+		leds := ledStrip.Slice()
+		for i := 0; i < count; i++ {
+			leds[i] = binary.LittleEndian.Uint32(colors[i*4:])
+		}
+		return
+	}
+	for i := 0; i < count; i++ {
+		led := SingleLED(binary.LittleEndian.Uint32(colors[i*4:]))
+		strip.SetColor(i, led.ToColor())
+	}
+}
+
+//Play writes the frames starting with the next frame (see Seek) to strips with the recorded timing until the last frame
+//or ctx is done. update is called after each frame was written, i.e. to Render the Config. frame is the index of the frame.
+//A done ctx is not an error.
+func (p *Player) Play(ctx context.Context, strips []WritableLEDs, update FrameFunc) error {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+	for p.next < len(p.frames) {
+		start := time.Now()
+		base := p.frames[p.next].timestamp
+		for ; p.next < len(p.frames); p.next++ {
+			wait := time.Duration(float64(p.frames[p.next].timestamp-base)/p.speed) - time.Since(start)
+			if wait > 0 {
+				timer.Reset(wait)
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-timer.C:
+				}
+			} else {
+				select {
+				case <-ctx.Done():
+					return nil
+				default:
+				}
+			}
+			err := p.ReadFrame(p.next, strips)
+			if err != nil {
+				return errors.Wrap(err, "player Play")
+			}
+			if update != nil {
+				err = update(uint64(p.next))
+				if err != nil {
+					return errors.Wrap(err, "player Play")
+				}
+			}
+		}
+		//A single frame stays on the LEDs anyway
+		if p.loop && len(p.frames) > 1 {
+			p.next = 0
+		}
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package rpiws281x
+
+import (
+	"github.com/pkg/errors"
+)
+
+//PowerModel describes the current drawn by one LED in mA. Red, Green, Blue and White are drawn at full value of the color
+//and proportional below. Idle is drawn in any case.
+type PowerModel struct {
+	Red   float64
+	Green float64
+	Blue  float64
+	White float64
+	Idle  float64
+}
+
+//Power model presets to be used with SetPowerModel
+var (
+	PowerNone           = PowerModel{}                                      // No estimation. Default
+	PowerTypicalWS2812B = PowerModel{Red: 16, Green: 11, Blue: 15, Idle: 1} // Typical WS2812B at 5 V
+)
+
+//SetPowerModel sets the current drawn by each LED of the strip with index stripIndex. It is needed for SetPowerLimit,
+//SetTotalPowerLimit and EstimatedCurrent.
+//This method can be called once the Config is initialized.
+func (c *Config) SetPowerModel(model PowerModel, stripIndex int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if stripIndex < 0 || stripIndex >= len(c.channels) {
+		return errors.Wrap(ErrConfigWrongIndex, "config SetPowerModel")
+	}
+	c.channels[stripIndex].powerModel = model
+	return nil
+}
+
+//SetPowerLimit sets the maximum current in mA for the strip with index stripIndex. 0 disables the limit.
+//Frames which would draw more are dimmed proportionally during Render.
+//This method can be called once the Config is initialized.
+func (c *Config) SetPowerLimit(milliamps float64, stripIndex int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if stripIndex < 0 || stripIndex >= len(c.channels) {
+		return errors.Wrap(ErrConfigWrongIndex, "config SetPowerLimit")
+	}
+	if milliamps < 0 {
+		return errors.Wrap(ErrWrongPowerLimit, "config SetPowerLimit")
+	}
+	c.channels[stripIndex].powerLimit = milliamps
+	return nil
+}
+
+//SetTotalPowerLimit sets the maximum current in mA for all strips of the Config together. 0 disables the limit.
+//Frames which would draw more are dimmed proportionally during Render. The limits per strip are applied first.
+//This method can be called once the Config is initialized.
+func (c *Config) SetTotalPowerLimit(milliamps float64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if milliamps < 0 {
+		return errors.Wrap(ErrWrongPowerLimit, "config SetTotalPowerLimit")
+	}
+	c.totalPowerLimit = milliamps
+	return nil
+}
+
+//EstimatedCurrent returns the estimated current in mA of the last rendered frame for the strip with index stripIndex
+//or of all strips if stripIndex is -1. The dimming by the limits is included.
+func (c *Config) EstimatedCurrent(stripIndex int) (float64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if stripIndex < -1 || stripIndex >= len(c.channels) {
+		return 0, errors.Wrap(ErrConfigWrongIndex, "config EstimatedCurrent")
+	}
+	var res float64
+	for curChanID := range c.channels {
+		if stripIndex == -1 || stripIndex == curChanID {
+			res += c.channels[curChanID].estimatedCurrent()
+		}
+	}
+	return res, nil
+}
+
+//estimates the current of all channels and sets the scales to stay within the limits
+func (c *Config) limitPower() {
+	var idleTotal, variableTotal float64
+	for curChanID := range c.channels {
+		curChannel := &c.channels[curChanID]
+		curChannel.powerScale = 256
+		curChannel.idleCurrent, curChannel.fullCurrent = 0, 0
+		if !curChannel.active || curChannel.powerModel == PowerNone {
+			continue
+		}
+		curChannel.estimateCurrent()
+		if curChannel.powerLimit > 0 {
+			curChannel.powerScale = powerScale(curChannel.idleCurrent, curChannel.fullCurrent, curChannel.powerLimit)
+		}
+		idleTotal += curChannel.idleCurrent
+		variableTotal += (curChannel.fullCurrent - curChannel.idleCurrent) * float64(curChannel.powerScale) / 256
+	}
+	if c.totalPowerLimit <= 0 || idleTotal+variableTotal <= c.totalPowerLimit {
+		return
+	}
+	//Dim all estimated channels by the same factor
+	totalScale := powerScale(idleTotal, idleTotal+variableTotal, c.totalPowerLimit)
+	for curChanID := range c.channels {
+		curChannel := &c.channels[curChanID]
+		if !curChannel.active || curChannel.powerModel == PowerNone {
+			continue
+		}
+		curChannel.powerScale = (curChannel.powerScale * totalScale) >> 8
+		if curChannel.powerScale == 0 {
+			curChannel.powerScale = 1
+		}
+	}
+}
+
+//returns the scale (1 - 256) so that a draw of full with idle stays within limit
+func powerScale(idle, full, limit float64) uint32 {
+	if full <= limit {
+		return 256
+	}
+	if limit <= idle {
+		//value * 1 >> 8 is 0
+		return 1
+	}
+	scale := uint32((limit - idle) / (full - idle) * 256)
+	if scale < 1 {
+		return 1
+	}
+	return scale
+}
+
+//sets idleCurrent and fullCurrent from the current LEDs of the channel
+func (ch *ledChannel) estimateCurrent() {
+	ledCount := ch.strip.TotalCount()
+	ledColors := ledColorCount(ch.stripType)
+	pipeline := ch.colorPipeline(ledCount)
+	var sums [4]uint64
+	for i := 0; i < ledCount; i++ {
+		curLED := pipeline.led(ch.strip.UInt32(i))
+		ledScale := pipeline.ledScale(i)
+		for j := 0; j < ledColors; j++ {
+			sums[j] += uint64(pipeline.value(curLED, ledScale, j))
+		}
+	}
+	ch.idleCurrent = ch.powerModel.Idle * float64(ledCount)
+	ch.fullCurrent = ch.idleCurrent
+	for j := 0; j < ledColors; j++ {
+		ch.fullCurrent += float64(sums[j]) / 255 * ch.powerModel.current(pipeline.shifts[j])
+	}
+}
+
+//returns the current for the color at shift in 0xWWRRGGBB
+func (pm PowerModel) current(shift uint8) float64 {
+	switch (shift / 8) & 0x3 {
+	case 0:
+		return pm.Blue
+	case 1:
+		return pm.Green
+	case 2:
+		return pm.Red
+	}
+	return pm.White
+}
+
+//returns the estimated current of the channel including the dimming
+func (ch *ledChannel) estimatedCurrent() float64 {
+	return ch.idleCurrent + (ch.fullCurrent-ch.idleCurrent)*float64(ch.powerScale)/256
+}
@@ -0,0 +1,145 @@
+package rpiws281x
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestPowerScale(t *testing.T) {
+	tests := []struct {
+		idle, full, limit float64
+		want              uint32
+	}{
+		{10, 100, 200, 256},
+		{10, 100, 100, 256},
+		{10, 110, 60, 128},
+		{0, 100, 25, 64},
+		{10, 100, 10, 1},
+		{10, 100, 5, 1},
+		{0, 1000000, 1, 1},
+	}
+	for _, test := range tests {
+		if got := powerScale(test.idle, test.full, test.limit); got != test.want {
+			t.Errorf("idle %v full %v limit %v: got %d want %d", test.idle, test.full, test.limit, got, test.want)
+		}
+	}
+}
+
+func TestLimitPower(t *testing.T) {
+	c, _ := New(DriverPWM)
+	white := NewLEDStrip(10)
+	white.Fill(0xffffff)
+	red := NewLEDStrip(5)
+	red.Fill(0xff0000)
+	for stripIndex, curStrip := range []*LEDStrip{white, red} {
+		err := c.SetStrip(curStrip, []uint32{18, 13}[stripIndex], WS2812Strip, stripIndex, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.SetBrightness(255, stripIndex)
+		c.SetPowerModel(PowerModel{Red: 20, Green: 20, Blue: 20, Idle: 1}, stripIndex)
+	}
+	//Without limits the white strip draws 10 + 600 mA and the red strip 5 + 100 mA
+	tests := []struct {
+		name       string
+		stripLimit [2]float64
+		totalLimit float64
+		wantScales [2]uint32
+		wantMA     [2]float64
+	}{
+		{"no limit", [2]float64{}, 0, [2]uint32{256, 256}, [2]float64{610, 105}},
+		{"strip limits above", [2]float64{610, 200}, 715, [2]uint32{256, 256}, [2]float64{610, 105}},
+		{"strip limit", [2]float64{310, 0}, 0, [2]uint32{128, 256}, [2]float64{310, 105}},
+		{"strip limit below idle", [2]float64{5, 0}, 0, [2]uint32{1, 256}, [2]float64{10 + 600.0/256, 105}},
+		{"total limit", [2]float64{}, 365, [2]uint32{128, 128}, [2]float64{310, 55}},
+		//The total limit dims the strips after their own limits
+		{"strip and total limit", [2]float64{310, 0}, 215, [2]uint32{64, 128}, [2]float64{160, 55}},
+	}
+	for _, test := range tests {
+		for stripIndex := range test.stripLimit {
+			err := c.SetPowerLimit(test.stripLimit[stripIndex], stripIndex)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		err := c.SetTotalPowerLimit(test.totalLimit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.limitPower()
+		var total float64
+		for stripIndex := range test.wantScales {
+			if got := c.channels[stripIndex].powerScale; got != test.wantScales[stripIndex] {
+				t.Errorf("%s: strip %d: got scale %d want %d", test.name, stripIndex, got, test.wantScales[stripIndex])
+			}
+			got, err := c.EstimatedCurrent(stripIndex)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.wantMA[stripIndex] {
+				t.Errorf("%s: strip %d: got %v mA want %v", test.name, stripIndex, got, test.wantMA[stripIndex])
+			}
+			total += got
+		}
+		if got, _ := c.EstimatedCurrent(-1); got != total {
+			t.Errorf("%s: got %v mA for all strips want %v", test.name, got, total)
+		}
+	}
+
+	//Strips without a power model are not limited and not counted
+	c.SetPowerModel(PowerNone, 1)
+	c.SetPowerLimit(0, 0)
+	c.SetTotalPowerLimit(310)
+	c.limitPower()
+	if c.channels[0].powerScale != 128 || c.channels[1].powerScale != 256 {
+		t.Errorf("without power model: got scales %d and %d want 128 and 256", c.channels[0].powerScale, c.channels[1].powerScale)
+	}
+	if got, _ := c.EstimatedCurrent(1); got != 0 {
+		t.Errorf("without power model: got %v mA want 0", got)
+	}
+}
+
+func TestPowerLimitRender(t *testing.T) {
+	strip := NewLEDStrip(4)
+	strip.Fill(0xffffff)
+	c, backend := newPWMTestConfig(t, strip, WS2812Strip)
+	c.SetPowerModel(PowerModel{Red: 20, Green: 20, Blue: 20}, 0)
+	c.SetPowerLimit(120, 0)
+	decoded := renderDecoded(t, c, backend)
+	for i := 0; i < strip.TotalCount(); i++ {
+		if got := decoded[0].UInt32(i); got != 0x7f7f7f {
+			t.Errorf("LED %d: got %x want 7f7f7f", i, got)
+		}
+	}
+	got, err := c.EstimatedCurrent(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 120 {
+		t.Errorf("got %v mA want 120", got)
+	}
+}
+
+func TestPowerErrors(t *testing.T) {
+	c, _ := New(DriverPWM)
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"SetPowerModel index", c.SetPowerModel(PowerTypicalWS2812B, 2), ErrConfigWrongIndex},
+		{"SetPowerLimit index", c.SetPowerLimit(100, -1), ErrConfigWrongIndex},
+		{"negative limit", c.SetPowerLimit(-1, 0), ErrWrongPowerLimit},
+		{"negative total limit", c.SetTotalPowerLimit(-1), ErrWrongPowerLimit},
+	}
+	for _, test := range tests {
+		if errors.Cause(test.err) != test.want {
+			t.Errorf("%s: got %v want %v", test.name, test.err, test.want)
+		}
+	}
+	_, err := c.EstimatedCurrent(-2)
+	if errors.Cause(err) != ErrConfigWrongIndex {
+		t.Errorf("EstimatedCurrent index: got %v want %v", err, ErrConfigWrongIndex)
+	}
+}
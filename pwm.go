@@ -26,7 +26,6 @@ import (
  */
 
 const (
-	pwmBitsPerOutputBit         = 3
 	registerPWMBusOffset uint32 = 0x0020c000
 
 	//Register Offsets
@@ -66,19 +65,11 @@ var (
 	registerValuePWMDmacDreq  = func(val uint32) uint32 { return ((val & 0xff) << 0) }
 )
 
-var pwmRegisterMem rpimemmap.MemMap
-var pwmDataMem rpimemmap.MemMap
+var pwmRegisterMem Memory
+var pwmDataMem [2]Memory // Two buffers. One is sent by DMA while the next frame is encoded into the other
+var pwmIdleBuffer int    // Index of the buffer in pwmDataMem which is not sent
 var activePWMChannels uint32
 
-type pwmPinDefinition struct {
-	pinNum  *rpigpio.Pin
-	altMode rpigpio.Mode
-}
-
-type pwmPinTable []pwmPinDefinition
-
-type pwmChannelType []pwmPinTable
-
 var (
 	pin12, _    = rpigpio.NewPin(12)
 	pin18, _    = rpigpio.NewPin(18)
@@ -87,8 +78,8 @@ var (
 	pin19, _    = rpigpio.NewPin(19)
 	pin41, _    = rpigpio.NewPin(41)
 	pin45, _    = rpigpio.NewPin(45)
-	pwmChannels = pwmChannelType{
-		pwmPinTable{ // Mapping of Pin to alternate function for PWM channel 0
+	pwmChannels = channelPinTable{
+		pinTable{ // Mapping of Pin to alternate function for PWM channel 0
 			{
 				pinNum:  pin12,
 				altMode: rpigpio.ModeAlternate0,
@@ -102,7 +93,7 @@ var (
 				altMode: rpigpio.ModeAlternate0,
 			},
 		},
-		pwmPinTable{ // Mapping of Pin to alternate function for PWM channel 1
+		pinTable{ // Mapping of Pin to alternate function for PWM channel 1
 			{
 				pinNum:  pin13,
 				altMode: rpigpio.ModeAlternate0,
@@ -123,25 +114,8 @@ var (
 	}
 )
 
-func (pt pwmPinDefinition) getAltMode() (rpigpio.Mode, error) {
-	return pt.altMode, nil
-}
-
-func (ptl pwmPinTable) getAltMode(pinNum uint32) (rpigpio.Mode, error) {
-	for _, curEntry := range ptl {
-		if curEntry.pinNum.Is(pinNum) {
-			return curEntry.getAltMode()
-		}
-	}
-	return 0, errors.Wrap(ErrPinNotAllowed, "")
-}
-
-func (pc pwmChannelType) getAltMode(chanNum int, pinNum uint32) (rpigpio.Mode, error) {
-	return pc[chanNum].getAltMode(pinNum)
-}
-
 //initializes pwm specific stuff like clock, pwm data, pwm device, dma cb
-func initializePWM(channels []ledChannel, frequency uint32) error {
+func initializePWM(channels []ledChannel, timing *symbolTiming) error {
 	var err error
 	logOutput("Initializing clock peripheral")
 	err = initializeClock()
@@ -150,7 +124,7 @@ func initializePWM(channels []ledChannel, frequency uint32) error {
 	}
 	logOutput("Done clock")
 	logOutput("Setup PWM clock")
-	err = clockSetupPwm(frequency)
+	err = clockSetupPwm(timing.divisor)
 	if err != nil {
 		return err
 	}
@@ -158,20 +132,19 @@ func initializePWM(channels []ledChannel, frequency uint32) error {
 
 	if pwmRegisterMem == nil {
 		logOutput("Initializing PWM")
-		pwmRegisterMem = rpimemmap.NewPeripheral(uint32(os.Getpagesize()))
-		err := pwmRegisterMem.Map(registerPWMBusOffset, rpimemmap.MemDevDefault, 0)
+		pwmRegisterMem, err = activeBackend.MapPeripheral(registerPWMBusOffset, uint32(os.Getpagesize()))
 		if err != nil {
 			return err
 		}
 		logOutput("Done pwm")
 		logOutput("PWM: " + pwmRegisterMem.String())
 	}
-	*rpimemmap.Reg32(pwmRegisterMem, registerOffsetPWMRng1) = 32 //32-bits per word to serialize
-	*rpimemmap.Reg32(pwmRegisterMem, registerOffsetPWMRng2) = 32 //32-bits per word to serialize
+	pwmRegisterMem.Write32(registerOffsetPWMRng1, 32) //32-bits per word to serialize
+	pwmRegisterMem.Write32(registerOffsetPWMRng2, 32) //32-bits per word to serialize
 	time.Sleep(10 * time.Microsecond)
-	*rpimemmap.Reg32(pwmRegisterMem, registerOffsetPWMCtl) = registerValuePWMCtlClrf1 // Clear Fifo
+	pwmRegisterMem.Write32(registerOffsetPWMCtl, registerValuePWMCtlClrf1) // Clear Fifo
 	time.Sleep(10 * time.Microsecond)
-	*rpimemmap.Reg32(pwmRegisterMem, registerOffsetPWMDmac) = registerValuePWMDmacEnab | registerValuePWMDmacPanic(7) | registerValuePWMDmacDreq(3)
+	pwmRegisterMem.Write32(registerOffsetPWMDmac, registerValuePWMDmacEnab|registerValuePWMDmacPanic(7)|registerValuePWMDmacDreq(3))
 	time.Sleep(10 * time.Microsecond)
 	var ctlSettings uint32
 	if channels[0].active || PWMAlwaysUseTwoChannel {
@@ -183,26 +156,29 @@ func initializePWM(channels []ledChannel, frequency uint32) error {
 	if channels[1].active || PWMAlwaysUseTwoChannel {
 		ctlSettings |= registerValuePWMCtlUsef2 | registerValuePWMCtlMode2
 		if channels[1].invert {
-			ctlSettings |= registerValuePWMCtlPola1
+			ctlSettings |= registerValuePWMCtlPola2
 		}
 	}
-	*rpimemmap.Reg32(pwmRegisterMem, registerOffsetPWMCtl) = ctlSettings
+	pwmRegisterMem.Write32(registerOffsetPWMCtl, ctlSettings)
 	time.Sleep(10 * time.Microsecond)
 	if channels[0].active || PWMAlwaysUseTwoChannel {
-		*rpimemmap.Reg32(pwmRegisterMem, registerOffsetPWMCtl) |= registerValuePWMCtlPwen1
+		pwmRegisterMem.Write32(registerOffsetPWMCtl, pwmRegisterMem.Read32(registerOffsetPWMCtl)|registerValuePWMCtlPwen1)
 	}
 	if channels[1].active || PWMAlwaysUseTwoChannel {
-		*rpimemmap.Reg32(pwmRegisterMem, registerOffsetPWMCtl) |= registerValuePWMCtlPwen2
+		pwmRegisterMem.Write32(registerOffsetPWMCtl, pwmRegisterMem.Read32(registerOffsetPWMCtl)|registerValuePWMCtlPwen2)
 	}
 	time.Sleep(10 * time.Microsecond)
 
-	if pwmDataMem != nil {
-		logOutput("Clearing PWM data storage")
-		err = pwmDataMem.Unmap()
-		if err != nil {
-			return err
+	for i := range pwmDataMem {
+		if pwmDataMem[i] != nil {
+			logOutput("Clearing PWM data storage")
+			err = pwmDataMem[i].Unmap()
+			if err != nil {
+				return err
+			}
+			pwmDataMem[i] = nil
+			logOutput("Done clearing")
 		}
-		logOutput("Done clearing")
 	}
 
 	var dataSize uint32
@@ -213,7 +189,7 @@ func initializePWM(channels []ledChannel, frequency uint32) error {
 			if (uint(curChannel.stripType) & sk6812ShiftMask) != 0 {
 				ledColors = 4
 			}
-			ledBitCount := curChannel.strip.TotalCount() * ledColors * 8 * pwmBitsPerOutputBit // Each LED has 8 Bit per Color which are each mapped to pwmBitsPerOutputBit Bits
+			ledBitCount := curChannel.strip.TotalCount() * ledColors * 8 * timing.format.Bits // Each LED has 8 Bit per Color which are each mapped to a symbol
 			thisByteCount := (uint32(ledBitCount>>3) & ^uint32(0x7)) + 8
 			//Larger channel will be the reference
 			thisByteCount += 32 //Spacing so that leds render. Don't know yet how to calculate
@@ -228,17 +204,19 @@ func initializePWM(channels []ledChannel, frequency uint32) error {
 	}
 	dataSize *= activePWMChannels
 	logOutput(fmt.Sprintf("Initializing PWM data storage. size: %d bytes", dataSize))
-	pwmDataMem = rpimemmap.NewUncached(dataSize)
 	allocationFlags := rpimemmap.UncachedMemFlagDirect
 	if curHardware.RPiType == rpihardware.RPiType1 {
 		allocationFlags = 0xc
 	}
-	err = pwmDataMem.Map(0, "", allocationFlags)
-	if err != nil {
-		return err
+	for i := range pwmDataMem {
+		pwmDataMem[i], err = activeBackend.MapUncached(dataSize, allocationFlags)
+		if err != nil {
+			return err
+		}
+		logOutput("PWM storage: " + pwmDataMem[i].String())
 	}
+	pwmIdleBuffer = 0
 	logOutput("Done PWM data storage")
-	logOutput("PWM storage: " + pwmDataMem.String())
 
 	//Initialize dma control block for pwm
 	logOutput("Initializing DmaCB")
@@ -266,12 +244,14 @@ func cleanupPWM() error {
 		}
 		pwmRegisterMem = nil
 	}
-	if pwmDataMem != nil {
-		err := pwmDataMem.Unmap()
-		if err != nil {
-			return errors.Wrap(err, "cleanup pwm data")
+	for i := range pwmDataMem {
+		if pwmDataMem[i] != nil {
+			err := pwmDataMem[i].Unmap()
+			if err != nil {
+				return errors.Wrap(err, "cleanup pwm data")
+			}
+			pwmDataMem[i] = nil
 		}
-		pwmDataMem = nil
 	}
 	err = cleanupDmaCBPWM()
 	if err != nil {
@@ -285,7 +265,7 @@ func stopPWM() error {
 	if pwmRegisterMem == nil {
 		return nil
 	}
-	*rpimemmap.Reg32(pwmRegisterMem, registerOffsetPWMCtl) = 0
+	pwmRegisterMem.Write32(registerOffsetPWMCtl, 0)
 	//Stop PWM clock
 	err := stopClockPWM()
 	if err != nil {
@@ -301,92 +281,70 @@ func statusPWM() string {
 	}
 	var res string
 	res += fmt.Sprintf("PWM Status:\n")
-	res += fmt.Sprintf("\tChan1: %t\n", (*rpimemmap.Reg32(pwmRegisterMem, registerOffsetPWMSta)&registerValuePWMStaSta1 != 0))
-	res += fmt.Sprintf("\tChan2: %t\n", (*rpimemmap.Reg32(pwmRegisterMem, registerOffsetPWMSta)&registerValuePWMStaSta2 != 0))
-	res += fmt.Sprintf("\tBuserror: %t\n", (*rpimemmap.Reg32(pwmRegisterMem, registerOffsetPWMSta)&registerValuePWMStaBerr != 0))
+	res += fmt.Sprintf("\tChan1: %t\n", (pwmRegisterMem.Read32(registerOffsetPWMSta)&registerValuePWMStaSta1 != 0))
+	res += fmt.Sprintf("\tChan2: %t\n", (pwmRegisterMem.Read32(registerOffsetPWMSta)&registerValuePWMStaSta2 != 0))
+	res += fmt.Sprintf("\tBuserror: %t\n", (pwmRegisterMem.Read32(registerOffsetPWMSta)&registerValuePWMStaBerr != 0))
 	res += fmt.Sprintf("PWM Chan 1:\n")
-	res += fmt.Sprintf("\tEnabled: %t\n", (*rpimemmap.Reg32(pwmRegisterMem, registerOffsetPWMCtl)&registerValuePWMCtlPwen1 != 0))
-	res += fmt.Sprintf("\tUse Serialiser: %t\n", (*rpimemmap.Reg32(pwmRegisterMem, registerOffsetPWMCtl)&registerValuePWMCtlMode1 != 0))
-	res += fmt.Sprintf("\tRepeat: %t\n", (*rpimemmap.Reg32(pwmRegisterMem, registerOffsetPWMCtl)&registerValuePWMCtlRptl1 != 0))
-	res += fmt.Sprintf("\tInverse: %t\n", (*rpimemmap.Reg32(pwmRegisterMem, registerOffsetPWMCtl)&registerValuePWMCtlPola1 != 0))
-	res += fmt.Sprintf("\tUse Fifo: %t\n", (*rpimemmap.Reg32(pwmRegisterMem, registerOffsetPWMCtl)&registerValuePWMCtlUsef1 != 0))
-	res += fmt.Sprintf("\tUse M/S: %t\n", (*rpimemmap.Reg32(pwmRegisterMem, registerOffsetPWMCtl)&registerValuePWMCtlMsen1 != 0))
+	res += fmt.Sprintf("\tEnabled: %t\n", (pwmRegisterMem.Read32(registerOffsetPWMCtl)&registerValuePWMCtlPwen1 != 0))
+	res += fmt.Sprintf("\tUse Serialiser: %t\n", (pwmRegisterMem.Read32(registerOffsetPWMCtl)&registerValuePWMCtlMode1 != 0))
+	res += fmt.Sprintf("\tRepeat: %t\n", (pwmRegisterMem.Read32(registerOffsetPWMCtl)&registerValuePWMCtlRptl1 != 0))
+	res += fmt.Sprintf("\tInverse: %t\n", (pwmRegisterMem.Read32(registerOffsetPWMCtl)&registerValuePWMCtlPola1 != 0))
+	res += fmt.Sprintf("\tUse Fifo: %t\n", (pwmRegisterMem.Read32(registerOffsetPWMCtl)&registerValuePWMCtlUsef1 != 0))
+	res += fmt.Sprintf("\tUse M/S: %t\n", (pwmRegisterMem.Read32(registerOffsetPWMCtl)&registerValuePWMCtlMsen1 != 0))
 	res += fmt.Sprintf("PWM Chan 2:\n")
-	res += fmt.Sprintf("\tEnabled: %t\n", (*rpimemmap.Reg32(pwmRegisterMem, registerOffsetPWMCtl)&registerValuePWMCtlPwen2 != 0))
-	res += fmt.Sprintf("\tUse Serialiser: %t\n", (*rpimemmap.Reg32(pwmRegisterMem, registerOffsetPWMCtl)&registerValuePWMCtlMode2 != 0))
-	res += fmt.Sprintf("\tRepeat: %t\n", (*rpimemmap.Reg32(pwmRegisterMem, registerOffsetPWMCtl)&registerValuePWMCtlRptl2 != 0))
-	res += fmt.Sprintf("\tInverse: %t\n", (*rpimemmap.Reg32(pwmRegisterMem, registerOffsetPWMCtl)&registerValuePWMCtlPola2 != 0))
-	res += fmt.Sprintf("\tUse Fifo: %t\n", (*rpimemmap.Reg32(pwmRegisterMem, registerOffsetPWMCtl)&registerValuePWMCtlUsef2 != 0))
-	res += fmt.Sprintf("\tUse M/S: %t\n", (*rpimemmap.Reg32(pwmRegisterMem, registerOffsetPWMCtl)&registerValuePWMCtlMsen2 != 0))
+	res += fmt.Sprintf("\tEnabled: %t\n", (pwmRegisterMem.Read32(registerOffsetPWMCtl)&registerValuePWMCtlPwen2 != 0))
+	res += fmt.Sprintf("\tUse Serialiser: %t\n", (pwmRegisterMem.Read32(registerOffsetPWMCtl)&registerValuePWMCtlMode2 != 0))
+	res += fmt.Sprintf("\tRepeat: %t\n", (pwmRegisterMem.Read32(registerOffsetPWMCtl)&registerValuePWMCtlRptl2 != 0))
+	res += fmt.Sprintf("\tInverse: %t\n", (pwmRegisterMem.Read32(registerOffsetPWMCtl)&registerValuePWMCtlPola2 != 0))
+	res += fmt.Sprintf("\tUse Fifo: %t\n", (pwmRegisterMem.Read32(registerOffsetPWMCtl)&registerValuePWMCtlUsef2 != 0))
+	res += fmt.Sprintf("\tUse M/S: %t\n", (pwmRegisterMem.Read32(registerOffsetPWMCtl)&registerValuePWMCtlMsen2 != 0))
 	return res
 }
 
-//outputs signals with PWM for the given channels
-func renderPWM(channels []ledChannel, frequency uint32) (int64, error) {
+//encodes the channel with index stripIndex or all channels if stripIndex is -1 into the idle buffer.
+//The buffer is sent with startPWM. Returns the time in microseconds needed to send it.
+func renderPWM(channels []ledChannel, stripIndex int, timing *symbolTiming) (int64, error) {
+	for curChanID := range channels {
+		curChannel := &channels[curChanID]
+		if curChannel.active && (stripIndex == -1 || stripIndex == curChanID) {
+			encodeChannel(curChannel, timing, false)
+		}
+	}
+	return writePWM(channels, timing), nil
+}
+
+//copies the encoded data of all active channels into the idle buffer. Returns the time in microseconds needed to send it.
+func writePWM(channels []ledChannel, timing *symbolTiming) int64 {
 	var protocolTime uint32
-	var bitPos int
-	bitPos = 31
-	for curChanID, curChannel := range channels {
+	idleMem := pwmDataMem[pwmIdleBuffer]
+	for curChanID := range channels {
+		curChannel := &channels[curChanID]
 		if !curChannel.active {
-			//In case -1 was supplied during render call
 			continue
 		}
-		var wordPos uint32
-		wordPos = uint32(curChanID)
-		scale := uint32((curChannel.brightness & 0xff)) + 1
-
-		//2.5 uS per bit to led @ 400000
-		//1.25 uS per bit to led @ 800000
-		bitTime := float32(2.5)
-		if frequency == 800000 {
-			bitTime = float32(1.25)
-		}
-		ledColors := 3 //Assume 3 colors per LED
-		// If our shift mask includes the highest nibble, then we have 4 LEDs, RBGW.
-		if (uint(curChannel.stripType) & sk6812ShiftMask) != 0 {
-			ledColors = 4
+		channelTime := channelProtocolTime(*curChannel, timing)
+		if channelTime > protocolTime {
+			protocolTime = channelTime
 		}
-		channelProtocolTime := uint32(float32(curChannel.strip.TotalCount()*ledColors*8) * bitTime)
-		if channelProtocolTime > protocolTime {
-			protocolTime = channelProtocolTime
-		}
-		for i := 0; i < curChannel.strip.TotalCount(); i++ {
-			curLED := curChannel.strip.UInt32(i)
-			var color = []uint8{
-				gammaTable[(((curLED>>curChannel.rshift)&0xff)*scale)>>8],
-				gammaTable[(((curLED>>curChannel.gshift)&0xff)*scale)>>8],
-				gammaTable[(((curLED>>curChannel.bshift)&0xff)*scale)>>8],
-				gammaTable[(((curLED>>curChannel.wshift)&0xff)*scale)>>8],
-			}
-			for j := 0; j < ledColors; j++ {
-				curColor := color[j]
-				for k := 7; k >= 0; k-- { // Bit per Color
-					// Inversion is handled by hardware for PWM, otherwise by software here
-					symbol := symbolLow
-					if (curColor & (1 << k)) != 0 {
-						symbol = symbolHigh
-					}
-					for l := 2; l >= 0; l-- { // Bit per Symbol
-						*rpimemmap.Reg32(pwmDataMem, wordPos*4) &= ^(1 << bitPos)
-						if (symbol & (1 << l)) != 0 {
-							*rpimemmap.Reg32(pwmDataMem, wordPos*4) |= (1 << bitPos)
-						}
-						bitPos--
-						if bitPos < 0 {
-							// Every other word is on the same channel for PWM if two channels are active and with fifo
-							if activePWMChannels == 2 {
-								wordPos += 2
-							} else {
-								wordPos++
-							}
-							bitPos = 31
-						}
-					}
-				}
-			}
+		//The idle buffer holds an older frame. Not encoded channels keep their last encoded data.
+		// Every other word is on the same channel for PWM if two channels are active and with fifo
+		// Inversion is handled by hardware for PWM
+		// A single channel starts at the first word regardless of its index
+		if activePWMChannels == 2 {
+			writeWords(idleMem, curChannel.encoded, uint32(curChanID), 2)
+		} else {
+			writeWords(idleMem, curChannel.encoded, 0, 1)
 		}
 	}
-	//fmt.Println(rpimemmap.Dump(pwmDataMem, 0))
-	// 300: Minimum time to wait for reset to occur in microseconds
-	return int64(protocolTime + 300), nil
+	return int64(protocolTime + timing.reset)
+}
+
+//starts sending the idle buffer with channel and swaps the buffers. The previous transfer must be done.
+func startPWM(channel uint32) error {
+	err := startDMA(channel, dmaCBRegisterMemPWM.BusAddr()+uint32(pwmIdleBuffer)*dmaCBSize)
+	if err != nil {
+		return err
+	}
+	pwmIdleBuffer ^= 1
+	return nil
 }
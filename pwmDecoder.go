@@ -0,0 +1,199 @@
+package rpiws281x
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+//PWMChannelLayout describes the content of one PWM channel for DecodePWM.
+type PWMChannelLayout struct {
+	LEDCount  int          // Number of LEDs sent on this channel. 0 if the channel is not used
+	StripType StripType    // StripType of the strip connected to the channel
+	Invert    bool         // Set if the data contains the inverted signal. The DMA data of PWM is never inverted as the hardware handles this
+	Symbols   SymbolFormat // Format of the symbols. DefaultSymbols if empty
+}
+
+//DecodedStrip holds the LEDs reconstructed by DecodePWM. It implements LEDs so it can be compared to the rendered strip.
+type DecodedStrip struct {
+	stripType StripType
+	colors    int
+	data      []uint8 // Color bytes in the order they were sent
+}
+
+//DecodePWM reconstructs the color bytes sent to each channel from the data words which were handed to the PWM FIFO.
+/*
+Provide one layout per PWM channel. If two layouts are given, the words of both channels are expected to be interleaved
+as done for two active channels (or PWMAlwaysUseTwoChannel). Leading low bits (idle line) are skipped and all data after the last
+LED has to be low (reset).
+
+The result has the same order as layout. Gamma and brightness are not reverted.
+
+The data of PCM uses the same encoding. Use a single layout with Invert set like the channel as PCM inverts by software.
+*/
+func DecodePWM(data []uint32, layout []PWMChannelLayout) ([]*DecodedStrip, error) {
+	if len(layout) == 0 || len(layout) > 2 {
+		return nil, errors.Wrap(ErrConfigWrongIndex, "DecodePWM")
+	}
+	res := make([]*DecodedStrip, len(layout))
+	for curChanID, curLayout := range layout {
+		var words []uint32
+		for i := curChanID; i < len(data); i += len(layout) {
+			words = append(words, data[i])
+		}
+		curStrip, err := decodePWMChannel(words, curLayout)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("DecodePWM channel %d", curChanID))
+		}
+		res[curChanID] = curStrip
+	}
+	return res, nil
+}
+
+//PWMLayout returns the layout of the PWM channels of c as needed by DecodePWM to decode a Render(-1).
+func (c *Config) PWMLayout() []PWMChannelLayout {
+	if c.driverType != DriverPWM {
+		return nil
+	}
+	var res []PWMChannelLayout
+	for _, curChannel := range c.channels {
+		if !curChannel.active {
+			res = append(res, PWMChannelLayout{})
+			continue
+		}
+		curLayout := PWMChannelLayout{
+			LEDCount:  curChannel.strip.TotalCount(),
+			StripType: curChannel.stripType,
+		}
+		if c.symbols != nil {
+			curLayout.Symbols = c.symbols.format
+		}
+		res = append(res, curLayout)
+	}
+	if !c.channels[0].active && !PWMAlwaysUseTwoChannel {
+		return res[1:]
+	}
+	if !c.channels[1].active && !PWMAlwaysUseTwoChannel {
+		return res[:1]
+	}
+	return res
+}
+
+//decodes the words of a single channel
+func decodePWMChannel(words []uint32, layout PWMChannelLayout) (*DecodedStrip, error) {
+	res := &DecodedStrip{
+		stripType: layout.StripType,
+		colors:    3,
+	}
+	if (uint(layout.StripType) & sk6812ShiftMask) != 0 {
+		res.colors = 4
+	}
+	totalBits := len(words) * 32
+	bitAt := func(pos int) uint8 {
+		bit := uint8(words[pos/32]>>(31-pos%32)) & 1
+		if layout.Invert {
+			bit ^= 1
+		}
+		return bit
+	}
+	format := layout.Symbols
+	if format == (SymbolFormat{}) {
+		format = DefaultSymbols
+	}
+	symbolHigh := format.symbol(1)
+	symbolLow := format.symbol(0)
+	bitPos := 0
+	if layout.LEDCount > 0 {
+		//Skip idle line before the first symbol
+		for bitPos < totalBits && bitAt(bitPos) == 0 {
+			bitPos++
+		}
+	}
+	res.data = make([]uint8, layout.LEDCount*res.colors)
+	for i := range res.data {
+		var curByte uint8
+		for k := 0; k < 8; k++ {
+			if bitPos+format.Bits > totalBits {
+				return nil, errors.Wrap(ErrDecodeTooShort, "decode channel")
+			}
+			var symbol uint64
+			for l := 0; l < format.Bits; l++ {
+				symbol = symbol<<1 | uint64(bitAt(bitPos))
+				bitPos++
+			}
+			switch symbol {
+			case symbolHigh:
+				curByte = curByte<<1 | 1
+			case symbolLow:
+				curByte = curByte << 1
+			default:
+				return nil, errors.Wrap(ErrDecodeSymbol, fmt.Sprintf("decode channel LED %d: 0b%0*b", i/res.colors, format.Bits, symbol))
+			}
+		}
+		res.data[i] = curByte
+	}
+	for ; bitPos < totalBits; bitPos++ {
+		if bitAt(bitPos) != 0 {
+			return nil, errors.Wrap(ErrDecodeNoReset, "decode channel")
+		}
+	}
+	return res, nil
+}
+
+//Bytes returns all color bytes in the order they were sent.
+func (d *DecodedStrip) Bytes() []uint8 {
+	return d.data
+}
+
+//LEDBytes returns the color bytes of the LED at position in the order they were sent. These are 3 or 4 bytes depending on the StripType.
+func (d *DecodedStrip) LEDBytes(position int) []uint8 {
+	if position < 0 || position >= d.TotalCount() {
+		return nil
+	}
+	return d.data[position*d.colors : (position+1)*d.colors]
+}
+
+//TotalCount returns the number of decoded LEDs.
+func (d *DecodedStrip) TotalCount() int {
+	return len(d.data) / d.colors
+}
+
+//UInt32 returns the decoded color as uint32. Format 0xWWRRGGBB
+func (d *DecodedStrip) UInt32(position int) uint32 {
+	ledBytes := d.LEDBytes(position)
+	if ledBytes == nil {
+		return 0
+	}
+	//Order of the bytes on the wire as done by renderPWM
+	shifts := []uint32{
+		uint32((d.stripType >> 16) & 0xff),
+		uint32((d.stripType >> 8) & 0xff),
+		uint32((d.stripType >> 0) & 0xff),
+		uint32((d.stripType >> 24) & 0xff),
+	}
+	var res uint32
+	for i, curByte := range ledBytes {
+		res |= uint32(curByte) << shifts[i]
+	}
+	return res
+}
+
+//Red returns the decoded red color amount at position.
+func (d *DecodedStrip) Red(position int) uint8 {
+	return uint8(d.UInt32(position) >> 16)
+}
+
+//Green returns the decoded green color amount at position.
+func (d *DecodedStrip) Green(position int) uint8 {
+	return uint8(d.UInt32(position) >> 8)
+}
+
+//Blue returns the decoded blue color amount at position.
+func (d *DecodedStrip) Blue(position int) uint8 {
+	return uint8(d.UInt32(position))
+}
+
+//White returns the decoded white color amount at position.
+func (d *DecodedStrip) White(position int) uint8 {
+	return uint8(d.UInt32(position) >> 24)
+}
@@ -0,0 +1,212 @@
+package rpiws281x
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+//bitWriter packs bits into words. The most significant bit of a word is sent first
+type bitWriter struct {
+	words []uint32
+	pos   int
+}
+
+func (w *bitWriter) write(bit uint8, count int) {
+	for i := 0; i < count; i++ {
+		if w.pos%32 == 0 {
+			w.words = append(w.words, 0)
+		}
+		if bit != 0 {
+			w.words[len(w.words)-1] |= 1 << (31 - w.pos%32)
+		}
+		w.pos++
+	}
+}
+
+//encodes data bit by bit like a WS281x expects it after idleBits low bits. Each bit is a symbol which starts
+//with its high bits. The words are followed by resetWords zero words.
+func referenceEncode(format SymbolFormat, idleBits int, data []uint8, resetWords int) []uint32 {
+	var w bitWriter
+	w.write(0, idleBits)
+	for _, curByte := range data {
+		for k := 7; k >= 0; k-- {
+			high := format.ZeroHigh
+			if curByte>>k&1 != 0 {
+				high = format.OneHigh
+			}
+			w.write(1, high)
+			w.write(0, format.Bits-high)
+		}
+	}
+	return append(w.words, make([]uint32, resetWords)...)
+}
+
+//interleaves the words of two channels like renderPWM. The shorter channel is filled with zero words
+func interleave(a, b []uint32) []uint32 {
+	var res []uint32
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var wordA, wordB uint32
+		if i < len(a) {
+			wordA = a[i]
+		}
+		if i < len(b) {
+			wordB = b[i]
+		}
+		res = append(res, wordA, wordB)
+	}
+	return res
+}
+
+func invertWords(words []uint32) []uint32 {
+	res := make([]uint32, len(words))
+	for i := range words {
+		res[i] = ^words[i]
+	}
+	return res
+}
+
+func TestDecodePWM(t *testing.T) {
+	format4 := SymbolFormat{Bits: 4, ZeroHigh: 1, OneHigh: 3}
+	tests := []struct {
+		name   string
+		data   []uint32
+		layout []PWMChannelLayout
+		want   [][]uint32 // Per channel
+	}{
+		{
+			name:   "GRB",
+			data:   referenceEncode(DefaultSymbols, 0, []uint8{0x34, 0x12, 0x56, 0xff, 0x00, 0x80, 0, 0, 0}, 2),
+			layout: []PWMChannelLayout{{LEDCount: 3, StripType: WS2812Strip}},
+			want:   [][]uint32{{0x123456, 0x00ff80, 0}},
+		},
+		{
+			name:   "GRBW",
+			data:   referenceEncode(DefaultSymbols, 0, []uint8{0x34, 0x12, 0x56, 0x78, 0xff, 0xff, 0xff, 0xff}, 1),
+			layout: []PWMChannelLayout{{LEDCount: 2, StripType: SK6812StripGRBW}},
+			want:   [][]uint32{{0x78123456, 0xffffffff}},
+		},
+		{
+			name:   "idle line before the data",
+			data:   referenceEncode(DefaultSymbols, 37, []uint8{0x01, 0x80, 0xaa}, 1),
+			layout: []PWMChannelLayout{{LEDCount: 1, StripType: WS2812Strip}},
+			want:   [][]uint32{{0x8001aa}},
+		},
+		{
+			name:   "other symbol format",
+			data:   referenceEncode(format4, 0, []uint8{0x34, 0x12, 0x56}, 1),
+			layout: []PWMChannelLayout{{LEDCount: 1, StripType: WS2812Strip, Symbols: format4}},
+			want:   [][]uint32{{0x123456}},
+		},
+		{
+			name: "two channels",
+			data: interleave(
+				referenceEncode(DefaultSymbols, 0, []uint8{0x34, 0x12, 0x56}, 1),
+				referenceEncode(DefaultSymbols, 0, []uint8{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}, 1),
+			),
+			layout: []PWMChannelLayout{
+				{LEDCount: 1, StripType: WS2812Strip},
+				{LEDCount: 2, StripType: SK6812StripGRBW},
+			},
+			want: [][]uint32{{0x123456}, {0x04020103, 0x08060507}},
+		},
+		{
+			name: "second channel unused",
+			data: interleave(referenceEncode(DefaultSymbols, 0, []uint8{0x34, 0x12, 0x56}, 1), nil),
+			layout: []PWMChannelLayout{
+				{LEDCount: 1, StripType: WS2812Strip},
+				{},
+			},
+			want: [][]uint32{{0x123456}, nil},
+		},
+		{
+			name:   "inverted",
+			data:   invertWords(referenceEncode(DefaultSymbols, 5, []uint8{0x34, 0x12, 0x56, 0xf0, 0x0f, 0x55}, 2)),
+			layout: []PWMChannelLayout{{LEDCount: 2, StripType: WS2812Strip, Invert: true}},
+			want:   [][]uint32{{0x123456, 0x0ff055}},
+		},
+	}
+	for _, test := range tests {
+		decoded, err := DecodePWM(test.data, test.layout)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if len(decoded) != len(test.want) {
+			t.Errorf("%s: got %d channels want %d", test.name, len(decoded), len(test.want))
+			continue
+		}
+		for chanID, curWant := range test.want {
+			if decoded[chanID].TotalCount() != len(curWant) {
+				t.Errorf("%s: channel %d has %d LEDs want %d", test.name, chanID, decoded[chanID].TotalCount(), len(curWant))
+				continue
+			}
+			for i, curLED := range curWant {
+				if decoded[chanID].UInt32(i) != curLED {
+					t.Errorf("%s: channel %d LED %d: got %x want %x", test.name, chanID, i, decoded[chanID].UInt32(i), curLED)
+				}
+			}
+		}
+	}
+}
+
+func TestDecodePWMErrors(t *testing.T) {
+	format4 := SymbolFormat{Bits: 4, ZeroHigh: 1, OneHigh: 3}
+	invalidSymbol := referenceEncode(DefaultSymbols, 0, []uint8{0x34, 0x12, 0x56}, 1)
+	invalidSymbol[0] |= 0xe0000000 // 0b111
+	tests := []struct {
+		name   string
+		data   []uint32
+		layout []PWMChannelLayout
+		want   error
+	}{
+		{
+			name:   "data after the last LED",
+			data:   referenceEncode(DefaultSymbols, 0, []uint8{0x34, 0x12, 0x56, 0x01, 0x02, 0x03}, 1),
+			layout: []PWMChannelLayout{{LEDCount: 1, StripType: WS2812Strip}},
+			want:   ErrDecodeNoReset,
+		},
+		{
+			name:   "invalid symbol",
+			data:   invalidSymbol,
+			layout: []PWMChannelLayout{{LEDCount: 1, StripType: WS2812Strip}},
+			want:   ErrDecodeSymbol,
+		},
+		{
+			name:   "reset instead of the second LED",
+			data:   referenceEncode(DefaultSymbols, 0, []uint8{0x34, 0x12, 0x56}, 2),
+			layout: []PWMChannelLayout{{LEDCount: 2, StripType: WS2812Strip}},
+			want:   ErrDecodeSymbol,
+		},
+		{
+			//One LED fills exactly 3 words with 4 bit symbols
+			name:   "data ends before the second LED",
+			data:   referenceEncode(format4, 0, []uint8{0x34, 0x12, 0x56}, 0),
+			layout: []PWMChannelLayout{{LEDCount: 2, StripType: WS2812Strip, Symbols: format4}},
+			want:   ErrDecodeTooShort,
+		},
+		{
+			name:   "second channel too short",
+			data:   interleave(referenceEncode(format4, 0, []uint8{0x34, 0x12, 0x56}, 0), referenceEncode(format4, 0, []uint8{1, 2, 3}, 0)),
+			layout: []PWMChannelLayout{{LEDCount: 1, StripType: WS2812Strip, Symbols: format4}, {LEDCount: 2, StripType: WS2812Strip, Symbols: format4}},
+			want:   ErrDecodeTooShort,
+		},
+		{
+			name: "no layout",
+			data: []uint32{0},
+			want: ErrConfigWrongIndex,
+		},
+		{
+			name:   "three layouts",
+			data:   []uint32{0},
+			layout: make([]PWMChannelLayout, 3),
+			want:   ErrConfigWrongIndex,
+		},
+	}
+	for _, test := range tests {
+		_, err := DecodePWM(test.data, test.layout)
+		if errors.Cause(err) != test.want {
+			t.Errorf("%s: got %v want %v", test.name, err, test.want)
+		}
+	}
+}
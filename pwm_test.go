@@ -0,0 +1,129 @@
+package rpiws281x
+
+import (
+	"testing"
+
+	"github.com/DerLukas15/rpigpio"
+)
+
+func TestRenderPWMSecondChannelOnly(t *testing.T) {
+	backend := NewSimulatedBackend()
+	err := SetBackend(backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := New(DriverPWM)
+	strip := NewLEDStrip(4)
+	for i := 0; i < strip.TotalCount(); i++ {
+		strip.SetDirect(i, 0x123456+uint32(i))
+	}
+	err = c.SetStrip(strip, 13, WS2812Strip, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetBrightness(255, 1)
+	err = c.Initialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+	err = c.Render(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := backend.LastTransfer(c.dmaChannel)
+	if len(data) == 0 || data[0] == 0 {
+		t.Fatalf("first word is not data: %v", data)
+	}
+	decoded, err := DecodePWM(data, c.PWMLayout())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < strip.TotalCount(); i++ {
+		if decoded[0].UInt32(i) != strip.UInt32(i) {
+			t.Errorf("LED %d: got %x want %x", i, decoded[0].UInt32(i), strip.UInt32(i))
+		}
+	}
+}
+
+func TestPWMInitializeRenderStop(t *testing.T) {
+	backend := NewSimulatedBackend()
+	err := SetBackend(backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := New(DriverPWM)
+	strips := []*LEDStrip{testStrip(4, false), testStrip(6, true)}
+	err = c.SetStrip(strips[0], 18, WS2812Strip, 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.SetStrip(strips[1], 13, SK6812StripGRBW, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetBrightness(255, 0)
+	c.SetBrightness(255, 1)
+	err = c.Initialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+	regs := pwmRegisterMem
+	clock := clockRegisterMem
+	checkRegister(t, "CTL", regs, registerOffsetPWMCtl, 0xffff,
+		registerValuePWMCtlPwen1|registerValuePWMCtlMode1|registerValuePWMCtlUsef1|registerValuePWMCtlPola1|
+			registerValuePWMCtlPwen2|registerValuePWMCtlMode2|registerValuePWMCtlUsef2)
+	checkRegister(t, "RNG1", regs, registerOffsetPWMRng1, 0xffffffff, 32)
+	checkRegister(t, "RNG2", regs, registerOffsetPWMRng2, 0xffffffff, 32)
+	checkRegister(t, "DMAC", regs, registerOffsetPWMDmac, 0xffffffff,
+		registerValuePWMDmacEnab|registerValuePWMDmacPanic(7)|registerValuePWMDmacDreq(3))
+	checkRegister(t, "clock CTL", clock, registerOffsetClkPwmCtl, registerValueClkCtlEnab|registerValueClkCtlBusy,
+		registerValueClkCtlEnab|registerValueClkCtlBusy)
+	checkRegister(t, "clock DIV", clock, registerOffsetClkPwmDiv, registerValueClkDivDivi(0xfff),
+		registerValueClkDivDivi(c.symbols.divisor))
+	checkPin(t, backend, 18, rpigpio.ModeAlternate5, 0)
+	checkPin(t, backend, 13, rpigpio.ModeAlternate0, 0)
+
+	err = c.Render(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transfer := backend.LastTransfer(c.dmaChannel)
+	fifo := backend.PWMFIFO()
+	if len(fifo) != len(transfer) {
+		t.Fatalf("FIFO has %d words, DMA transferred %d", len(fifo), len(transfer))
+	}
+	for i := range fifo {
+		if fifo[i] != transfer[i] {
+			t.Fatalf("FIFO word %d: got %x want %x", i, fifo[i], transfer[i])
+		}
+	}
+	//Hardware inversion. The data is not inverted
+	decoded, err := DecodePWM(transfer, c.PWMLayout())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDecoded(t, decoded, strips[0], strips[1])
+
+	//The second frame is sent from the other buffer
+	strips[0].SetDirect(0, 0xabcdef)
+	err = c.Render(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err = DecodePWM(backend.LastTransfer(c.dmaChannel), c.PWMLayout())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDecoded(t, decoded, strips[0], strips[1])
+
+	err = c.Stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRegister(t, "CTL after Stop", regs, registerOffsetPWMCtl, 0xffffffff, 0)
+	checkRegister(t, "clock CTL after Stop", clock, registerOffsetClkPwmCtl, registerValueClkCtlBusy, 0)
+	checkPin(t, backend, 18, rpigpio.ModeOut, 0)
+	checkPin(t, backend, 13, rpigpio.ModeOut, 0)
+}
@@ -0,0 +1,190 @@
+package rpiws281x
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+/*
+Recording file format. All values are little endian.
+
+Header:
+  magic         8 bytes "RPIWSREC"
+  version       uint16
+  channel count uint16
+  per channel:
+    strip type  uint32
+    LED count   uint32  0 if the channel is not used
+
+Frames until the end of the file:
+  timestamp     uint64  Nanoseconds since the first frame
+  channel mask  uint8   Bit n is set if channel n is part of the frame
+  per channel in the mask:
+    colors      LED count * uint32  Format 0xWWRRGGBB
+*/
+
+const (
+	recordMagic       = "RPIWSREC"
+	recordVersion     = 1
+	recordMaxChannels = 8 // Channels in the mask of a frame
+)
+
+//RecordedChannel describes a channel of a recording.
+type RecordedChannel struct {
+	StripType StripType
+	LEDCount  int
+}
+
+//Recorder writes the LEDs of each Render of a Config to a recording which can be played with a Player.
+type Recorder struct {
+	w        *bufio.Writer
+	channels []RecordedChannel // Set with the first frame
+	start    time.Time
+	buf      []byte
+}
+
+//NewRecorder returns a Recorder writing to w. Use Config.SetRecorder to start recording.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{
+		w: bufio.NewWriter(w),
+	}
+}
+
+//Flush writes all buffered frames to the underlying writer.
+func (r *Recorder) Flush() error {
+	return r.w.Flush()
+}
+
+//SetRecorder sets the Recorder which records every Render. nil stops recording. The Recorder is not flushed.
+//A Recorder only records the strips of its first frame. Render returns ErrRecordStripsChanged for other strips.
+//This method can be called once the Config is initialized.
+func (c *Config) SetRecorder(r *Recorder) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.recorder = r
+}
+
+//writes the header for channels
+func (r *Recorder) writeHeader(channels []ledChannel) error {
+	if len(channels) > recordMaxChannels {
+		return errors.Wrap(ErrRecordChannelCount, "write header")
+	}
+	r.channels = make([]RecordedChannel, len(channels))
+	header := make([]byte, 0, len(recordMagic)+4+len(channels)*8)
+	header = append(header, recordMagic...)
+	header = appendUint16(header, recordVersion)
+	header = appendUint16(header, uint16(len(channels)))
+	for curChanID := range channels {
+		r.channels[curChanID] = channels[curChanID].recorded()
+		header = appendUint32(header, uint32(r.channels[curChanID].StripType))
+		header = appendUint32(header, uint32(r.channels[curChanID].LEDCount))
+	}
+	_, err := r.w.Write(header)
+	return err
+}
+
+//writes the channel with index stripIndex or all channels if stripIndex is -1 as one frame
+func (r *Recorder) record(channels []ledChannel, stripIndex int) error {
+	now := time.Now()
+	if r.channels == nil {
+		err := r.writeHeader(channels)
+		if err != nil {
+			return errors.Wrap(err, "record")
+		}
+		r.start = now
+	}
+	if !r.matches(channels) {
+		return errors.Wrap(ErrRecordStripsChanged, "record")
+	}
+	var mask uint8
+	for curChanID, curChannel := range r.channels {
+		if curChannel.LEDCount > 0 && (stripIndex == -1 || stripIndex == curChanID) {
+			mask |= 1 << curChanID
+		}
+	}
+	r.buf = appendUint64(r.buf[:0], uint64(now.Sub(r.start)))
+	r.buf = append(r.buf, mask)
+	for curChanID, curChannel := range r.channels {
+		if mask&(1<<curChanID) == 0 {
+			continue
+		}
+		for i := 0; i < curChannel.LEDCount; i++ {
+			r.buf = appendUint32(r.buf, channels[curChanID].strip.UInt32(i))
+		}
+	}
+	_, err := r.w.Write(r.buf)
+	if err != nil {
+		return errors.Wrap(err, "record")
+	}
+	return nil
+}
+
+//reports whether the strips of channels match the header. The Config might have been initialized with other strips meanwhile
+func (r *Recorder) matches(channels []ledChannel) bool {
+	if len(channels) != len(r.channels) {
+		return false
+	}
+	for curChanID := range channels {
+		if channels[curChanID].recorded() != r.channels[curChanID] {
+			return false
+		}
+	}
+	return true
+}
+
+//returns the description of the channel in a recording. Unused channels have no LEDs
+func (ch *ledChannel) recorded() RecordedChannel {
+	if !ch.active {
+		return RecordedChannel{}
+	}
+	return RecordedChannel{
+		StripType: ch.stripType,
+		LEDCount:  ch.strip.TotalCount(),
+	}
+}
+
+func appendUint16(buf []byte, val uint16) []byte {
+	return append(buf, byte(val), byte(val>>8))
+}
+
+func appendUint32(buf []byte, val uint32) []byte {
+	return append(buf, byte(val), byte(val>>8), byte(val>>16), byte(val>>24))
+}
+
+func appendUint64(buf []byte, val uint64) []byte {
+	return appendUint32(appendUint32(buf, uint32(val)), uint32(val>>32))
+}
+
+//reads the header of a recording
+func readRecordHeader(r io.Reader) ([]RecordedChannel, error) {
+	header := make([]byte, len(recordMagic)+4)
+	_, err := io.ReadFull(r, header)
+	if err != nil {
+		return nil, errors.Wrap(ErrRecordFormat, err.Error())
+	}
+	if string(header[:len(recordMagic)]) != recordMagic {
+		return nil, ErrRecordFormat
+	}
+	if binary.LittleEndian.Uint16(header[len(recordMagic):]) != recordVersion {
+		return nil, ErrRecordVersion
+	}
+	channelCount := int(binary.LittleEndian.Uint16(header[len(recordMagic)+2:]))
+	if channelCount > recordMaxChannels {
+		return nil, ErrRecordFormat
+	}
+	channelData := make([]byte, channelCount*8)
+	_, err = io.ReadFull(r, channelData)
+	if err != nil {
+		return nil, errors.Wrap(ErrRecordFormat, err.Error())
+	}
+	channels := make([]RecordedChannel, channelCount)
+	for i := range channels {
+		channels[i].StripType = StripType(binary.LittleEndian.Uint32(channelData[i*8:]))
+		channels[i].LEDCount = int(binary.LittleEndian.Uint32(channelData[i*8+4:]))
+	}
+	return channels, nil
+}
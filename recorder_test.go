@@ -0,0 +1,310 @@
+package rpiws281x
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+//recordingStrips returns empty strips for the channels of p
+func recordingStrips(p *Player) []WritableLEDs {
+	var res []WritableLEDs
+	for _, curChannel := range p.Channels() {
+		res = append(res, NewLEDStrip(curChannel.LEDCount))
+	}
+	return res
+}
+
+func sameFrames(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+//records three frames with a RGB strip (index 0) and a RGBW strip (index 1). The second frame only renders strip 1.
+//Returns the recording and the colors of each frame per strip
+func recordTestFrames(t *testing.T, frameTime time.Duration) ([]byte, [][2][]uint32) {
+	t.Helper()
+	err := SetBackend(NewSimulatedBackend())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := New(DriverPWM)
+	strips := [2]*LEDStrip{NewLEDStrip(3), NewLEDStrip(2)}
+	err = c.SetStrip(strips[0], 18, WS2812Strip, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.SetStrip(strips[1], 13, SK6812StripGRBW, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Initialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+	var recording bytes.Buffer
+	recorder := NewRecorder(&recording)
+	c.SetRecorder(recorder)
+	var want [][2][]uint32
+	for frame, stripIndex := range []int{-1, 1, -1} {
+		for i := range strips {
+			if stripIndex == -1 || stripIndex == i {
+				strips[i].Fill(uint32(frame+1) * 0x01010101 << uint(i))
+			}
+		}
+		err = c.Render(stripIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var colors [2][]uint32
+		for i := range strips {
+			colors[i] = append([]uint32(nil), strips[i].Slice()...)
+		}
+		want = append(want, colors)
+		time.Sleep(frameTime)
+	}
+	err = recorder.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return recording.Bytes(), want
+}
+
+func TestRecorderPlayer(t *testing.T) {
+	recording, want := recordTestFrames(t, 20*time.Millisecond)
+	p, err := NewPlayer(bytes.NewReader(recording))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantChannels := []RecordedChannel{{WS2812Strip, 3}, {SK6812StripGRBW, 2}}
+	channels := p.Channels()
+	if len(channels) != len(wantChannels) || channels[0] != wantChannels[0] || channels[1] != wantChannels[1] {
+		t.Fatalf("got channels %v want %v", channels, wantChannels)
+	}
+	if p.FrameCount() != 3 {
+		t.Fatalf("got %d frames want 3", p.FrameCount())
+	}
+	if p.Duration() < 40*time.Millisecond || p.Duration() > time.Second {
+		t.Errorf("got duration %v want about 40ms", p.Duration())
+	}
+	strips := recordingStrips(p)
+	for frame := range want {
+		err = p.ReadFrame(frame, strips)
+		if err != nil {
+			t.Fatal(err)
+		}
+		//Strip 0 keeps the colors of frame 0 during frame 1
+		for i := range strips {
+			for position, wantLED := range want[frame][i] {
+				if got := strips[i].UInt32(position); got != wantLED {
+					t.Errorf("frame %d strip %d LED %d: got %x want %x", frame, i, position, got, wantLED)
+				}
+			}
+		}
+	}
+	err = p.ReadFrame(3, strips)
+	if errors.Cause(err) != ErrConfigWrongIndex {
+		t.Errorf("ReadFrame 3: got %v want %v", err, ErrConfigWrongIndex)
+	}
+	//Channels without a strip are skipped
+	err = p.ReadFrame(0, strips[:1])
+	if err != nil {
+		t.Error(err)
+	}
+
+	tests := []struct {
+		position time.Duration
+		want     int
+	}{
+		{0, 0},
+		{p.frames[1].timestamp - 1, 0},
+		{p.frames[1].timestamp, 1},
+		{p.frames[2].timestamp + time.Millisecond, 2},
+		{time.Hour, 2},
+	}
+	for _, test := range tests {
+		p.Seek(test.position)
+		if p.next != test.want {
+			t.Errorf("Seek %v: got frame %d want %d", test.position, p.next, test.want)
+		}
+	}
+	for _, frame := range []int{-1, 3} {
+		err = p.SeekFrame(frame)
+		if errors.Cause(err) != ErrConfigWrongIndex {
+			t.Errorf("SeekFrame %d: got %v want %v", frame, err, ErrConfigWrongIndex)
+		}
+	}
+	err = p.SetSpeed(0)
+	if errors.Cause(err) != ErrWrongSpeed {
+		t.Errorf("SetSpeed 0: got %v want %v", err, ErrWrongSpeed)
+	}
+}
+
+func TestPlayerPlay(t *testing.T) {
+	recording, want := recordTestFrames(t, 20*time.Millisecond)
+	p, err := NewPlayer(bytes.NewReader(recording))
+	if err != nil {
+		t.Fatal(err)
+	}
+	strips := recordingStrips(p)
+	play := func(ctx context.Context, update FrameFunc) []uint64 {
+		t.Helper()
+		var frames []uint64
+		err := p.Play(ctx, strips, func(frame uint64) error {
+			frames = append(frames, frame)
+			if update != nil {
+				return update(frame)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return frames
+	}
+
+	//The frames are played from the next frame with the recorded timing
+	err = p.SeekFrame(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	frames := play(context.Background(), nil)
+	if want := []uint64{1, 2}; !sameFrames(frames, want) {
+		t.Errorf("from frame 1: got frames %v want %v", frames, want)
+	}
+	if elapsed := time.Since(start); elapsed < p.frames[2].timestamp-p.frames[1].timestamp {
+		t.Errorf("from frame 1: played in %v", elapsed)
+	}
+	if strips[1].UInt32(0) != want[2][1][0] {
+		t.Errorf("from frame 1: got %x want the last frame %x", strips[1].UInt32(0), want[2][1][0])
+	}
+	if frames := play(context.Background(), nil); len(frames) != 0 {
+		t.Errorf("after the last frame: got frames %v want none", frames)
+	}
+
+	//Loop until cancelled. The first frame of each round is played without waiting
+	p.SetLoop(true)
+	err = p.SetSpeed(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Seek(0)
+	updates := 0
+	ctx, cancel := context.WithCancel(context.Background())
+	frames = play(ctx, func(frame uint64) error {
+		updates++
+		if updates == 6 {
+			cancel()
+		}
+		return nil
+	})
+	cancel()
+	if want := []uint64{0, 1, 2, 0, 1, 2}; !sameFrames(frames, want) {
+		t.Errorf("loop: got frames %v want %v", frames, want)
+	}
+	if p.next != 0 {
+		t.Errorf("loop: got next frame %d want 0", p.next)
+	}
+
+	//Cancelled while waiting for the next frame
+	p.SetLoop(false)
+	p.SetSpeed(1)
+	ctx, cancel = context.WithCancel(context.Background())
+	start = time.Now()
+	frames = play(ctx, func(frame uint64) error {
+		cancel()
+		return nil
+	})
+	if !sameFrames(frames, []uint64{0}) || p.next != 1 {
+		t.Errorf("cancel: got frames %v and next frame %d want [0] and 1", frames, p.next)
+	}
+	if elapsed := time.Since(start); elapsed >= p.frames[1].timestamp {
+		t.Errorf("cancel: returned after %v", elapsed)
+	}
+	if frames := play(ctx, nil); len(frames) != 0 {
+		t.Errorf("cancelled: got frames %v want none", frames)
+	}
+}
+
+func TestRecorderErrors(t *testing.T) {
+	var recording bytes.Buffer
+	err := NewRecorder(&recording).record(make([]ledChannel, recordMaxChannels+1), -1)
+	if errors.Cause(err) != ErrRecordChannelCount {
+		t.Errorf("%d channels: got %v want %v", recordMaxChannels+1, err, ErrRecordChannelCount)
+	}
+
+	//The Config is initialized again with a longer strip
+	err = SetBackend(NewSimulatedBackend())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := New(DriverPWM)
+	for _, count := range []int{3, 4} {
+		err = c.SetStrip(NewLEDStrip(count), 18, WS2812Strip, 0, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = c.Initialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count == 3 {
+			c.SetRecorder(NewRecorder(&recording))
+			err = c.Render(-1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.Stop()
+		}
+	}
+	err = c.Render(-1)
+	c.Stop()
+	if errors.Cause(err) != ErrRecordStripsChanged {
+		t.Errorf("changed strip: got %v want %v", err, ErrRecordStripsChanged)
+	}
+}
+
+func TestPlayerErrors(t *testing.T) {
+	recording, _ := recordTestFrames(t, 0)
+	badMagic := append([]byte("RPIWSREX"), recording[len(recordMagic):]...)
+	badVersion := append([]byte(nil), recording...)
+	badVersion[len(recordMagic)] = recordVersion + 1
+	tests := []struct {
+		name      string
+		recording []byte
+		want      error
+	}{
+		{"empty", nil, ErrRecordFormat},
+		{"magic", badMagic, ErrRecordFormat},
+		{"version", badVersion, ErrRecordVersion},
+		{"truncated header", recording[:len(recordMagic)+4+3], ErrRecordFormat},
+		{"truncated frame header", recording[:len(recording)-5*4-4], ErrRecordFormat},
+		{"truncated colors", recording[:len(recording)-1], ErrRecordFormat},
+	}
+	for _, test := range tests {
+		_, err := NewPlayer(bytes.NewReader(test.recording))
+		if errors.Cause(err) != test.want {
+			t.Errorf("%s: got %v want %v", test.name, err, test.want)
+		}
+	}
+	//A recording without frames
+	p, err := NewPlayer(bytes.NewReader(recording[:len(recordMagic)+4+2*8]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.FrameCount() != 0 || p.Duration() != 0 {
+		t.Errorf("got %d frames and duration %v want none", p.FrameCount(), p.Duration())
+	}
+}
@@ -0,0 +1,118 @@
+package rpiws281x
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	renderPollInterval = 100 * time.Microsecond // Interval to check if the DMA transfer has ended
+	renderTimeout      = 1 * time.Second        // Additional time after which a render is considered failed
+)
+
+//RenderAsync starts rendering like Render. The returned channel receives the result once the data is sent and the LEDs latched it.
+//The channel is closed afterwards. Meanwhile the next frame can be computed as the LEDs are encoded before RenderAsync returns.
+//
+//A following Render or RenderAsync waits for the previous one anyway. With PWM the next frame is encoded into a second
+//buffer while the previous one is still sent.
+func (c *Config) RenderAsync(stripIndex int) (<-chan error, error) {
+	c.mu.Lock()
+	err := c.render(stripIndex)
+	lastRender := c.lastRender
+	c.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- c.waitRender(context.Background(), lastRender)
+		close(done)
+	}()
+	return done, nil
+}
+
+//Wait blocks until the last render is sent and the LEDs latched the data or ctx is done.
+func (c *Config) Wait(ctx context.Context) error {
+	c.mu.Lock()
+	if !c.initialized {
+		c.mu.Unlock()
+		return nil
+	}
+	lastRender := c.lastRender
+	c.mu.Unlock()
+	return c.waitRender(ctx, lastRender)
+}
+
+//waits for the last render. c.mu has to be held
+func (c *Config) wait(ctx context.Context) error {
+	return c.waitRender(ctx, c.lastRender)
+}
+
+//renderWait holds the timing of a render which is shared by all waits for it
+type renderWait struct {
+	start    time.Time
+	waitTime int64 // Microseconds needed to send the data including the reset
+	reset    int64 // Microseconds the line has to be idle after the transfer
+
+	mu  sync.Mutex
+	end time.Time // First time the transfer was seen done
+}
+
+//remembers the render which was just started. c.mu has to be held
+func (c *Config) renderStarted() {
+	c.lastRender = &renderWait{
+		start:    time.Now(),
+		waitTime: c.renderWaitTime,
+		reset:    int64(c.symbols.reset),
+	}
+}
+
+//waits for the render r which needs waitTime microseconds including the reset.
+//The reset is counted from the end of the transfer so the LEDs latch even if the transfer finished late.
+func (c *Config) waitRender(ctx context.Context, r *renderWait) error {
+	if r == nil {
+		return nil
+	}
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+	for {
+		var remaining time.Duration
+		r.mu.Lock()
+		end := r.end
+		if end.IsZero() {
+			remaining = time.Duration(r.waitTime-r.reset)*time.Microsecond - time.Since(r.start)
+			if remaining <= 0 {
+				if c.driverType == DriverSPI {
+					//SPI is done once the transfer returned, which is start
+					end = r.start
+				} else if doneDMA(c.dmaChannel) {
+					//The exact end is unknown. Count the reset from the first time END is seen
+					end = time.Now()
+				} else if -remaining > renderTimeout {
+					r.mu.Unlock()
+					return errors.Wrap(ErrRenderTimeout, "wait")
+				} else {
+					remaining = renderPollInterval
+				}
+				r.end = end
+			}
+		}
+		r.mu.Unlock()
+		if !end.IsZero() {
+			remaining = time.Duration(r.reset)*time.Microsecond - time.Since(end)
+			if remaining <= 0 {
+				return nil
+			}
+		}
+		timer.Reset(remaining)
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "wait")
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package rpiws281x
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestRenderAsync(t *testing.T) {
+	strip := testStrip(8, false)
+	c, backend := newPWMTestConfig(t, strip, WS2812Strip)
+	for frame := 0; frame < 3; frame++ {
+		strip.SetDirect(0, uint32(frame))
+		done, err := c.RenderAsync(-1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case err = <-done:
+			if err != nil {
+				t.Fatalf("frame %d: %v", frame, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("frame %d: no result", frame)
+		}
+		if _, ok := <-done; ok {
+			t.Fatalf("frame %d: channel not closed", frame)
+		}
+		decoded, err := DecodePWM(backend.LastTransfer(c.dmaChannel), c.PWMLayout())
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkDecoded(t, decoded, strip)
+	}
+	err := c.Wait(context.Background())
+	if err != nil {
+		t.Errorf("Wait after RenderAsync: %v", err)
+	}
+	c.Stop()
+	_, err = c.RenderAsync(-1)
+	if errors.Cause(err) != ErrConfigNotInitialized {
+		t.Errorf("RenderAsync after Stop: got %v want %v", err, ErrConfigNotInitialized)
+	}
+	err = c.Wait(context.Background())
+	if err != nil {
+		t.Errorf("Wait after Stop: %v", err)
+	}
+}
+
+func TestWaitContext(t *testing.T) {
+	c, backend := newPWMTestConfig(t, testStrip(8, false), WS2812Strip)
+	backend.setStallDMA(true)
+	err := c.Render(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = c.Wait(ctx)
+	if errors.Cause(err) != context.Canceled {
+		t.Errorf("cancelled: got %v want %v", err, context.Canceled)
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = c.Wait(ctx)
+	if errors.Cause(err) != context.DeadlineExceeded {
+		t.Errorf("deadline: got %v want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > renderTimeout/2 {
+		t.Errorf("deadline: Wait returned after %v", elapsed)
+	}
+}
+
+func TestRenderTimeout(t *testing.T) {
+	c, backend := newPWMTestConfig(t, testStrip(8, false), WS2812Strip)
+	backend.setStallDMA(true)
+	done, err := c.RenderAsync(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	err = c.Wait(context.Background())
+	if errors.Cause(err) != ErrRenderTimeout {
+		t.Errorf("Wait: got %v want %v", err, ErrRenderTimeout)
+	}
+	if elapsed := time.Since(start); elapsed < renderTimeout {
+		t.Errorf("Wait: timed out after %v", elapsed)
+	}
+	select {
+	case err = <-done:
+		if errors.Cause(err) != ErrRenderTimeout {
+			t.Errorf("RenderAsync: got %v want %v", err, ErrRenderTimeout)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("RenderAsync: no result")
+	}
+	//The next render waits for the previous one
+	err = c.Render(-1)
+	if errors.Cause(err) != ErrRenderTimeout {
+		t.Errorf("Render: got %v want %v", err, ErrRenderTimeout)
+	}
+}
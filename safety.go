@@ -0,0 +1,163 @@
+package rpiws281x
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	safetyMu       sync.Mutex
+	activeConfigs  = make(map[*Config]struct{}) // All initialized configs
+	safetySignals  chan os.Signal               // Set while safety is enabled
+	safetyShutdown chan struct{}                // Closed to end the signal handler
+)
+
+//EnableSafety installs handlers for SIGINT and SIGTERM. On such a signal all initialized Configs are stopped with StopAll
+//and the handlers are removed.
+/*
+If raise is set, the signal is raised again afterwards so the process terminates as without the handlers.
+Go can't tell if other handlers exist. If the program handles SIGINT or SIGTERM itself (i.e. with signal.Notify), raise has
+to be false. Its handlers receive the signal as usual and would receive it twice otherwise.
+
+Use StopAllOnPanic to do the same on a panic.
+*/
+func EnableSafety(raise bool) {
+	safetyMu.Lock()
+	defer safetyMu.Unlock()
+	if safetySignals != nil {
+		return
+	}
+	safetySignals = make(chan os.Signal, 1)
+	safetyShutdown = make(chan struct{})
+	signal.Notify(safetySignals, syscall.SIGINT, syscall.SIGTERM)
+	go handleSafetySignals(safetySignals, safetyShutdown, raise)
+}
+
+//DisableSafety removes the handlers installed by EnableSafety.
+func DisableSafety() {
+	safetyMu.Lock()
+	defer safetyMu.Unlock()
+	if safetySignals == nil {
+		return
+	}
+	signal.Stop(safetySignals)
+	close(safetyShutdown)
+	safetySignals = nil
+	safetyShutdown = nil
+}
+
+//StopAllOnPanic stops all initialized Configs with StopAll if the program panics. The panic continues afterwards.
+//It has to be deferred directly, i.e. `defer rpiws281x.StopAllOnPanic()` at the beginning of main and every goroutine.
+/*
+The panic is recovered to stop the Configs and raised again with the same value. The stack of the goroutine is not unwound
+in between, so the trace still contains the frames of the original panic below StopAllOnPanic. The message is marked as
+recovered and repanicked. Recovering functions deferred before StopAllOnPanic get the value of the panic as usual.
+*/
+func StopAllOnPanic() {
+	if r := recover(); r != nil {
+		StopAll()
+		panic(r)
+	}
+}
+
+//StopAll turns off all LEDs of all initialized Configs and stops them. This includes the used DMA channels.
+//The pins are set to output low afterwards.
+func StopAll() error {
+	safetyMu.Lock()
+	configs := make([]*Config, 0, len(activeConfigs))
+	for curConfig := range activeConfigs {
+		configs = append(configs, curConfig)
+	}
+	safetyMu.Unlock()
+	var firstErr error
+	for _, curConfig := range configs {
+		err := curConfig.stopSafely()
+		if err != nil && firstErr == nil {
+			firstErr = errors.Wrap(err, "StopAll")
+		}
+	}
+	return firstErr
+}
+
+//waits for a signal, stops all configs and raises the signal again if raise is set
+func handleSafetySignals(signals chan os.Signal, shutdown chan struct{}, raise bool) {
+	select {
+	case <-shutdown:
+		return
+	case sig := <-signals:
+		logOutput("Got signal " + sig.String() + ". Stopping all configs")
+		StopAll()
+		//Only removes our channel. Handlers of the program stay installed
+		DisableSafety()
+		if raise {
+			//Without handlers the default action of Go terminates the process
+			syscall.Kill(os.Getpid(), sig.(syscall.Signal))
+		}
+	}
+}
+
+//blanks all LEDs and stops the config and its DMA channel
+func (c *Config) stopSafely() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.initialized {
+		return nil
+	}
+	//Blank the LEDs even if the last render failed
+	c.wait(context.Background())
+	err := c.renderBlank()
+	if c.driverType != DriverSPI {
+		stopDMA(c.dmaChannel)
+	}
+	stopErr := c.stop()
+	if err != nil {
+		return err
+	}
+	return stopErr
+}
+
+//sends all LEDs turned off directly to the hardware and waits until they latched. The previous render has to be done.
+//Unlike render, the Recorder, the color settings, segments and power limits are skipped. c.mu has to be held
+func (c *Config) renderBlank() error {
+	for i := range c.channels {
+		if c.channels[i].active {
+			// Inversion is handled by hardware for PWM
+			encodeBlank(&c.channels[i], c.symbols, c.driverType != DriverPWM && c.channels[i].invert)
+		}
+	}
+	var err error
+	switch c.driverType {
+	case DriverPWM:
+		c.renderWaitTime = writePWM(c.channels, c.symbols)
+		err = startPWM(c.dmaChannel)
+	case DriverPCM:
+		c.renderWaitTime = writePCM(c.channels, c.symbols)
+		err = startDMA(c.dmaChannel, dmaCBRegisterMemPCM.BusAddr())
+	case DriverSPI:
+		c.renderWaitTime, err = writeSPI(c.channels, c.symbols)
+	}
+	if err != nil {
+		return err
+	}
+	c.renderStarted()
+	return c.wait(context.Background())
+}
+
+//adds c to the configs stopped by StopAll
+func registerConfig(c *Config) {
+	safetyMu.Lock()
+	defer safetyMu.Unlock()
+	activeConfigs[c] = struct{}{}
+}
+
+//removes c from the configs stopped by StopAll
+func unregisterConfig(c *Config) {
+	safetyMu.Lock()
+	defer safetyMu.Unlock()
+	delete(activeConfigs, c)
+}
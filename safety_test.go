@@ -0,0 +1,211 @@
+package rpiws281x
+
+import (
+	"bytes"
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestStopAllDuringRender(t *testing.T) {
+	err := SetBackend(NewSimulatedBackend())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := New(DriverPWM)
+	strip := NewLEDStrip(300)
+	strip.Fill(0xff00ff)
+	err = c.SetStrip(strip, 18, WS2812Strip, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Initialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+	done := make(chan error)
+	go func() {
+		for {
+			err := c.Render(-1)
+			if err != nil {
+				done <- err
+				return
+			}
+		}
+	}()
+	err = StopAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = <-done
+	if errors.Cause(err) != ErrConfigNotInitialized {
+		t.Fatalf("Render after StopAll: %v", err)
+	}
+	if strip.UInt32(0) != 0xff00ff {
+		t.Fatal("strip not restored after StopAll")
+	}
+}
+
+func TestStopAllBlanksHardware(t *testing.T) {
+	backend := NewSimulatedBackend()
+	err := SetBackend(backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := New(DriverPWM)
+	strips := []*LEDStrip{testStrip(4, false), testStrip(6, true)}
+	err = c.SetStrip(strips[0], 18, WS2812Strip, 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.SetStrip(strips[1], 13, SK6812StripGRBW, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Initialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+	var recording bytes.Buffer
+	recorder := NewRecorder(&recording)
+	c.SetRecorder(recorder)
+	//Would turn the LEDs on with all colors 0
+	for stripIndex := range strips {
+		c.SetBrightness(255, stripIndex)
+		c.SetGammaCurve(&GammaCurve{0: 0x10, 255: 0xff}, stripIndex)
+		c.SetPowerModel(PowerTypicalWS2812B, stripIndex)
+		c.SetPowerLimit(1, stripIndex)
+	}
+	err = c.Render(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layout := c.PWMLayout()
+	err = StopAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodePWM(backend.LastTransfer(c.dmaChannel), layout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDecoded(t, decoded, NewLEDStrip(4), NewLEDStrip(6))
+	//Not recorded
+	err = recorder.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewPlayer(bytes.NewReader(recording.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.FrameCount() != 1 {
+		t.Errorf("got %d recorded frames want 1", p.FrameCount())
+	}
+}
+
+func TestStopAllBlanksPCM(t *testing.T) {
+	backend := NewSimulatedBackend()
+	err := SetBackend(backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := New(DriverPCM)
+	strip := testStrip(5, false)
+	err = c.SetStrip(strip, 21, WS2812Strip, 0, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetBrightness(255, 0)
+	err = c.Initialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+	err = c.Render(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = StopAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	//PCM inverts by software
+	transfer := backend.LastTransfer(c.dmaChannel)
+	if transfer[len(transfer)-1] != 0xffffffff {
+		t.Errorf("last word %x is not idle", transfer[len(transfer)-1])
+	}
+	decoded, err := DecodePWM(transfer, []PWMChannelLayout{{
+		LEDCount:  strip.TotalCount(),
+		StripType: WS2812Strip,
+		Invert:    true,
+		Symbols:   c.symbols.format,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDecoded(t, decoded, NewLEDStrip(5))
+	if strip.UInt32(0) != 0x123456 {
+		t.Errorf("strip changed by StopAll: %x", strip.UInt32(0))
+	}
+}
+
+func TestSafetySignal(t *testing.T) {
+	c, _ := newPWMTestConfig(t, testStrip(4, false), WS2812Strip)
+	own := make(chan os.Signal, 2)
+	signal.Notify(own, syscall.SIGTERM)
+	defer signal.Stop(own)
+	//The handler of the test stays installed. Raising again would deliver the signal twice
+	EnableSafety(false)
+	defer DisableSafety()
+	err := syscall.Kill(os.Getpid(), syscall.SIGTERM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-own:
+	case <-time.After(time.Second):
+		t.Fatal("signal not delivered to the handler of the program")
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		err = c.Render(-1)
+		if errors.Cause(err) == ErrConfigNotInitialized {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Config not stopped: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-own:
+		t.Error("signal delivered twice")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestStopAllOnPanic(t *testing.T) {
+	c, _ := newPWMTestConfig(t, testStrip(4, false), WS2812Strip)
+	var recovered interface{}
+	func() {
+		defer func() {
+			recovered = recover()
+		}()
+		defer StopAllOnPanic()
+		panic("test panic")
+	}()
+	if recovered != "test panic" {
+		t.Errorf("got panic %v want test panic", recovered)
+	}
+	err := c.Render(-1)
+	if errors.Cause(err) != ErrConfigNotInitialized {
+		t.Errorf("Render after panic: got %v want %v", err, ErrConfigNotInitialized)
+	}
+}
@@ -0,0 +1,254 @@
+package rpiws281x
+
+import (
+	"image/color"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+//Segment is a window into a parent strip which can be used as an independent LEDs. The colors are stored in the parent.
+/*
+A Segment covers length LEDs of the parent starting at offset. With reverse, position 0 is the last LED of the window.
+With mirror, each position is set on both halves of the window, so TotalCount is half of the length (rounded up).
+Position 0 is the outer LED then and with reverse the LED in the center.
+
+The brightness of a Segment is only applied if it is added to the Config with AddSegment.
+*/
+type Segment struct {
+	parent     WritableLEDs
+	offset     int
+	length     int
+	reverse    bool
+	mirror     bool
+	brightness uint8
+}
+
+//NewSegment returns a Segment over length LEDs of parent starting at offset. The brightness is 255.
+func NewSegment(parent WritableLEDs, offset, length int, reverse, mirror bool) (*Segment, error) {
+	if offset < 0 || length <= 0 || offset+length > parent.TotalCount() {
+		return nil, errors.Wrap(ErrWrongStripRange, "new segment")
+	}
+	return &Segment{
+		parent:     parent,
+		offset:     offset,
+		length:     length,
+		reverse:    reverse,
+		mirror:     mirror,
+		brightness: 255,
+	}, nil
+}
+
+//returns the position in the parent and the mirrored position. Positions not available are -1
+func (s *Segment) positions(position int) (int, int) {
+	count := s.TotalCount()
+	if position < 0 || position >= count {
+		return -1, -1
+	}
+	if s.reverse {
+		position = count - 1 - position
+	}
+	first := s.offset + position
+	if !s.mirror {
+		return first, -1
+	}
+	second := s.offset + s.length - 1 - position
+	if second == first {
+		return first, -1
+	}
+	return first, second
+}
+
+//Brightness returns the brightness of the segment.
+func (s *Segment) Brightness() uint8 {
+	return s.brightness
+}
+
+//SetBrightness sets the brightness of the segment. It is combined with the brightness of the strip set by Config.SetBrightness.
+func (s *Segment) SetBrightness(brightness uint8) {
+	s.brightness = brightness
+}
+
+//TotalCount returns the number of LEDs in the segment.
+func (s *Segment) TotalCount() int {
+	if s.mirror {
+		return (s.length + 1) / 2
+	}
+	return s.length
+}
+
+//Red returns the red color amount at position.
+func (s *Segment) Red(position int) uint8 {
+	first, _ := s.positions(position)
+	return s.parent.Red(first)
+}
+
+//Green returns the green color amount at position.
+func (s *Segment) Green(position int) uint8 {
+	first, _ := s.positions(position)
+	return s.parent.Green(first)
+}
+
+//Blue returns the blue color amount at position.
+func (s *Segment) Blue(position int) uint8 {
+	first, _ := s.positions(position)
+	return s.parent.Blue(first)
+}
+
+//White returns the white color amount at position.
+func (s *Segment) White(position int) uint8 {
+	first, _ := s.positions(position)
+	return s.parent.White(first)
+}
+
+//UInt32 returns the color as uint32. Format 0xWWRRGGBB
+func (s *Segment) UInt32(position int) uint32 {
+	first, _ := s.positions(position)
+	return s.parent.UInt32(first)
+}
+
+//SetColor sets the color from color.Color at position.
+func (s *Segment) SetColor(position int, c color.Color) {
+	first, second := s.positions(position)
+	if first < 0 {
+		return
+	}
+	s.parent.SetColor(first, c)
+	if second >= 0 {
+		s.parent.SetColor(second, c)
+	}
+}
+
+//directLEDs are LEDs which can store a color value without conversion like LEDStrip
+type directLEDs interface {
+	SetDirect(position int, val uint32)
+}
+
+//SetDirect sets the color value for LED at position directly. Format 0xWWRRGGBB
+func (s *Segment) SetDirect(position int, val uint32) {
+	first, second := s.positions(position)
+	if first < 0 {
+		return
+	}
+	direct, ok := s.parent.(directLEDs)
+	if !ok {
+		led := SingleLED(val)
+		s.parent.SetColor(first, led.ToColor())
+		if second >= 0 {
+			s.parent.SetColor(second, led.ToColor())
+		}
+		return
+	}
+	direct.SetDirect(first, val)
+	if second >= 0 {
+		direct.SetDirect(second, val)
+	}
+}
+
+//AddSegment applies the brightness of segment to the strip with index stripIndex. The parent of segment has to be the strip.
+/*
+For a virtual strip the parent has to be the LEDs given to SetVirtualStrip. The segment is added to both channels then
+and stripIndex may be either of them.
+
+The parent can only be checked if the type of the LEDs is comparable. Otherwise any LEDs of the same type are accepted.
+If segments overlap, the last added segment is used.
+This method can be called once the Config is initialized.
+*/
+func (c *Config) AddSegment(segment *Segment, stripIndex int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if stripIndex < 0 || stripIndex >= len(c.channels) {
+		return errors.Wrap(ErrConfigWrongIndex, "config AddSegment")
+	}
+	if !c.channels[stripIndex].active || !sameLEDs(c.channels[stripIndex].userStrip(), segment.parent) {
+		return errors.Wrap(ErrWrongSegmentParent, "config AddSegment")
+	}
+	for _, curChanID := range c.stripChannels(stripIndex) {
+		c.channels[curChanID].segments = append(c.channels[curChanID].segments, segment)
+	}
+	return nil
+}
+
+//returns the LEDs set by the user. This is the virtual strip for channels set by SetVirtualStrip
+func (ch *ledChannel) userStrip() LEDs {
+	if window, ok := ch.strip.(*stripWindow); ok {
+		return window.leds
+	}
+	return ch.strip
+}
+
+//returns the indexes of the channels sending the strip with index stripIndex. These are both channels for a virtual strip
+func (c *Config) stripChannels(stripIndex int) []int {
+	window, ok := c.channels[stripIndex].strip.(*stripWindow)
+	if !ok {
+		return []int{stripIndex}
+	}
+	var res []int
+	for curChanID := range c.channels {
+		other, ok := c.channels[curChanID].strip.(*stripWindow)
+		if ok && c.channels[curChanID].active && other.parts == window.parts {
+			res = append(res, curChanID)
+		}
+	}
+	return res
+}
+
+//reports whether a and b are the same LEDs. Comparing LEDs with == panics if the type is not comparable
+//(i.e. a struct with a slice). Such LEDs can't be told apart and are the same if the types match.
+func sameLEDs(a LEDs, b LEDs) bool {
+	typeA := reflect.TypeOf(a)
+	if typeA != reflect.TypeOf(b) {
+		return false
+	}
+	if typeA != nil && !typeA.Comparable() {
+		return true
+	}
+	return a == b
+}
+
+//ClearSegments removes all segments added with AddSegment from the strip with index stripIndex.
+//For a virtual strip the segments of both channels are removed.
+//This method can be called once the Config is initialized.
+func (c *Config) ClearSegments(stripIndex int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if stripIndex < 0 || stripIndex >= len(c.channels) {
+		return errors.Wrap(ErrConfigWrongIndex, "config ClearSegments")
+	}
+	for _, curChanID := range c.stripChannels(stripIndex) {
+		c.channels[curChanID].segments = nil
+	}
+	return nil
+}
+
+//returns the brightness scale (1 - 256) of the segments for each LED of the channel or nil if there are no segments
+func (ch *ledChannel) segmentScales(ledCount int) []uint32 {
+	if len(ch.segments) == 0 {
+		return nil
+	}
+	if cap(ch.segmentScale) < ledCount {
+		ch.segmentScale = make([]uint32, ledCount)
+	}
+	scales := ch.segmentScale[:ledCount]
+	for i := range scales {
+		scales[i] = 256
+	}
+	window, isWindow := ch.strip.(*stripWindow)
+	for _, curSegment := range ch.segments {
+		scale := uint32(curSegment.brightness) + 1
+		if !isWindow {
+			for i := curSegment.offset; i < curSegment.offset+curSegment.length && i < ledCount; i++ {
+				scales[i] = scale
+			}
+			continue
+		}
+		//Segments of a virtual strip use its positions
+		for i := range scales {
+			position := window.position(i)
+			if position >= curSegment.offset && position < curSegment.offset+curSegment.length {
+				scales[i] = scale
+			}
+		}
+	}
+	return scales
+}
@@ -0,0 +1,189 @@
+package rpiws281x
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+//colorOnly is WritableLEDs without SetDirect
+type colorOnly struct {
+	LEDs
+	strip         *LEDStrip
+	setColorCalls int
+}
+
+func (l *colorOnly) SetColor(position int, c color.Color) {
+	l.setColorCalls++
+	l.strip.SetColor(position, c)
+}
+
+func TestSegmentSetDirect(t *testing.T) {
+	strip := NewLEDStrip(6)
+	segment, err := NewSegment(strip, 1, 4, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	segment.SetDirect(0, 0x80112233)
+	if strip.UInt32(2) != 0x80112233 || strip.UInt32(3) != 0x80112233 {
+		t.Fatalf("got %x %x", strip.UInt32(2), strip.UInt32(3))
+	}
+	wrappedStrip := NewLEDStrip(6)
+	wrapped := &colorOnly{LEDs: wrappedStrip, strip: wrappedStrip}
+	segment, _ = NewSegment(wrapped, 0, 6, false, false)
+	segment.SetDirect(5, 0x01020304)
+	if wrapped.UInt32(5) != 0x01020304 || wrapped.setColorCalls != 1 {
+		t.Fatalf("got %x with %d calls", wrapped.UInt32(5), wrapped.setColorCalls)
+	}
+}
+
+func TestSegmentOnVirtualStrip(t *testing.T) {
+	backend := NewSimulatedBackend()
+	err := SetBackend(backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := New(DriverPWM)
+	strip := NewLEDStrip(6)
+	strip.Fill(0xffffff)
+	err = c.SetVirtualStrip(strip, WS2812Strip, [2]VirtualStripPart{
+		{Pin: 18, Offset: 0, Count: 3},
+		{Pin: 13, Offset: 3, Count: 3, Reverse: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetBrightness(255, 0)
+	c.SetBrightness(255, 1)
+	segment, _ := NewSegment(strip, 2, 2, false, false)
+	segment.SetBrightness(0)
+	err = c.AddSegment(segment, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Initialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+	err = c.Render(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodePWM(backend.LastTransfer(c.dmaChannel), c.PWMLayout())
+	if err != nil {
+		t.Fatal(err)
+	}
+	//Channel 1 sends the virtual positions 5, 4, 3
+	want := [2][]uint32{{0xffffff, 0xffffff, 0}, {0xffffff, 0xffffff, 0}}
+	for chanID := range want {
+		for i, wantLED := range want[chanID] {
+			if decoded[chanID].UInt32(i) != wantLED {
+				t.Errorf("channel %d LED %d: got %x want %x", chanID, i, decoded[chanID].UInt32(i), wantLED)
+			}
+		}
+	}
+	if err := c.AddSegment(segment, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddSegment(&Segment{parent: NewLEDStrip(6), length: 1}, 0); err == nil {
+		t.Fatal("segment of another strip added")
+	}
+}
+
+//taggedLEDs is WritableLEDs of a type which is not comparable
+type taggedLEDs struct {
+	*LEDStrip
+	tags []string
+}
+
+func TestSegmentNotComparableLEDs(t *testing.T) {
+	backend := NewSimulatedBackend()
+	err := SetBackend(backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := New(DriverPWM)
+	strips := []taggedLEDs{{NewLEDStrip(3), nil}, {NewLEDStrip(3), nil}}
+	for stripIndex, curStrip := range strips {
+		curStrip.Fill(0xffffff)
+		err = c.SetStrip(curStrip, []uint32{18, 13}[stripIndex], WS2812Strip, stripIndex, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.SetBrightness(255, stripIndex)
+	}
+	err = c.Initialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+	segment, _ := NewSegment(strips[0], 1, 1, false, false)
+	segment.SetBrightness(0)
+	err = c.AddSegment(segment, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.AddSegment(&Segment{parent: NewLEDStrip(3), length: 1}, 0)
+	if errors.Cause(err) != ErrWrongSegmentParent {
+		t.Errorf("segment of another type: got %v want %v", err, ErrWrongSegmentParent)
+	}
+	//Only the channel with the index gets the segment
+	decoded := renderDecoded(t, c, backend)
+	want := [2][]uint32{{0xffffff, 0, 0xffffff}, {0xffffff, 0xffffff, 0xffffff}}
+	for chanID := range want {
+		for i, wantLED := range want[chanID] {
+			if decoded[chanID].UInt32(i) != wantLED {
+				t.Errorf("channel %d LED %d: got %x want %x", chanID, i, decoded[chanID].UInt32(i), wantLED)
+			}
+		}
+	}
+	err = c.ClearSegments(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded = renderDecoded(t, c, backend)
+	if decoded[0].UInt32(1) != 0xffffff {
+		t.Errorf("LED 1 after ClearSegments: got %x want ffffff", decoded[0].UInt32(1))
+	}
+}
+
+func TestClearSegmentsVirtualStrip(t *testing.T) {
+	c, _ := New(DriverPWM)
+	strip := NewLEDStrip(6)
+	err := c.SetVirtualStrip(strip, WS2812Strip, [2]VirtualStripPart{
+		{Pin: 18, Offset: 0, Count: 3},
+		{Pin: 13, Offset: 3, Count: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	segment, _ := NewSegment(strip, 2, 2, false, false)
+	err = c.AddSegment(segment, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.channels[0].segments) != 1 || len(c.channels[1].segments) != 1 {
+		t.Fatalf("got %d and %d segments want 1 each", len(c.channels[0].segments), len(c.channels[1].segments))
+	}
+	err = c.ClearSegments(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.channels[0].segments) != 0 || len(c.channels[1].segments) != 0 {
+		t.Errorf("got %d and %d segments after ClearSegments want none", len(c.channels[0].segments), len(c.channels[1].segments))
+	}
+	//A strip of the same LEDs on the other channel is not part of the virtual strip
+	err = c.SetStrip(strip, 13, WS2812Strip, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.AddSegment(segment, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.channels[0].segments) != 1 || len(c.channels[1].segments) != 0 {
+		t.Errorf("got %d and %d segments want 1 and 0", len(c.channels[0].segments), len(c.channels[1].segments))
+	}
+}
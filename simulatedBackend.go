@@ -0,0 +1,398 @@
+package rpiws281x
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/DerLukas15/rpigpio"
+	"github.com/DerLukas15/rpihardware"
+)
+
+const (
+	simulatedPeripheralBusBase uint32 = 0x7e000000 // Bus address of the peripherals as seen by the DMA engine
+	simulatedUncachedBusBase   uint32 = 0xc0000000 // Bus address of the first uncached allocation
+	simulatedMaxControlBlocks         = 1024       // Guard against control blocks pointing to each other
+)
+
+//Hardware used by the SimulatedBackend. Behaves like a Raspberry Pi 2 / 3.
+var simulatedHardware = rpihardware.Hardware{
+	RPiType:       rpihardware.RPiType2,
+	PhysAddrBase:  0x3f000000,
+	VideocoreBase: simulatedUncachedBusBase,
+	OscFreq:       19200000,
+	Desc:          "Simulated",
+}
+
+//SimulatedBackend is a Backend which models the needed peripherals in memory. No hardware access and no root is needed.
+/*
+The following behaviour is modelled:
+
+Clock: The BUSY bit follows the ENAB and KILL bits if the password is supplied.
+
+PWM: Words written to the FIFO are collected and can be read with PWMFIFO. Setting CLRF1 clears the FIFO.
+
+PCM: Words written to the FIFO are collected and can be read with PCMFIFO. Setting TXCLR clears the FIFO.
+
+SPI: The data of the last transfer of any opened device can be read with SPIData.
+
+DMA: Setting ACTIVE on an enabled channel processes the chain of control blocks immediately. Afterwards ACTIVE is cleared and END is set.
+The data of the last transfer of a channel can be read with LastTransfer.
+
+Use SetBackend to activate a SimulatedBackend before initializing a Config.
+*/
+type SimulatedBackend struct {
+	mu           sync.Mutex
+	regions      []*simulatedMemory
+	nextUncached uint32
+	pinModes     map[uint32]rpigpio.Mode
+	pinLevels    map[uint32]int
+	pwmFIFO      []uint32
+	pcmFIFO      []uint32
+	spiData      []byte
+	lastTransfer map[uint32][]uint32
+	stallDMA     bool // DMA transfers are started but never end
+}
+
+//NewSimulatedBackend returns a new SimulatedBackend without any mapped memory.
+func NewSimulatedBackend() *SimulatedBackend {
+	return &SimulatedBackend{
+		nextUncached: simulatedUncachedBusBase,
+		pinModes:     make(map[uint32]rpigpio.Mode),
+		pinLevels:    make(map[uint32]int),
+		lastTransfer: make(map[uint32][]uint32),
+	}
+}
+
+//Hardware returns the simulated hardware.
+func (b *SimulatedBackend) Hardware() (*rpihardware.Hardware, error) {
+	return &simulatedHardware, nil
+}
+
+//MapPeripheral returns the simulated registers of the peripheral at busOffset.
+func (b *SimulatedBackend) MapPeripheral(busOffset uint32, size uint32) (Memory, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.mapRegion(busOffset, simulatedPeripheralBusBase+busOffset, size), nil
+}
+
+//MapUncached returns simulated memory which can be used by the simulated DMA engine.
+func (b *SimulatedBackend) MapUncached(size uint32, flags uint32) (Memory, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	m := b.mapRegion(0, b.nextUncached, size)
+	b.nextUncached += (size + 0xfff) & ^uint32(0xfff) // Keep allocations page aligned
+	return m, nil
+}
+
+//InitializeGPIO does nothing.
+func (b *SimulatedBackend) InitializeGPIO() error {
+	return nil
+}
+
+//PinMode stores the mode of pin.
+func (b *SimulatedBackend) PinMode(pin *rpigpio.Pin, mode rpigpio.Mode) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pinModes[pin.UInt32()] = mode
+	return nil
+}
+
+//PinSet stores the value of pin.
+func (b *SimulatedBackend) PinSet(pin *rpigpio.Pin, value int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if value != 0 {
+		value = 1
+	}
+	b.pinLevels[pin.UInt32()] = value
+	return nil
+}
+
+//PinState returns the last mode and value set for pin.
+func (b *SimulatedBackend) PinState(pin uint32) (rpigpio.Mode, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.pinModes[pin], b.pinLevels[pin]
+}
+
+//PWMFIFO returns all words written to the PWM FIFO since it was last cleared.
+func (b *SimulatedBackend) PWMFIFO() []uint32 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]uint32(nil), b.pwmFIFO...)
+}
+
+//PCMFIFO returns all words written to the PCM FIFO since it was last cleared.
+func (b *SimulatedBackend) PCMFIFO() []uint32 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]uint32(nil), b.pcmFIFO...)
+}
+
+//SPIData returns the data of the last SPI transfer.
+func (b *SimulatedBackend) SPIData() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.spiData...)
+}
+
+//OpenSPI returns a simulated SPI device. device is ignored.
+func (b *SimulatedBackend) OpenSPI(device string, speedHz uint32) (SPIDevice, error) {
+	return &simulatedSPI{backend: b}, nil
+}
+
+//LastTransfer returns the words moved by the last completed transfer of dmaChannel.
+func (b *SimulatedBackend) LastTransfer(dmaChannel uint32) []uint32 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]uint32(nil), b.lastTransfer[dmaChannel]...)
+}
+
+//mapRegion creates a new region. busOffset identifies the peripheral and is 0 for uncached memory.
+func (b *SimulatedBackend) mapRegion(busOffset uint32, busAddr uint32, size uint32) *simulatedMemory {
+	m := &simulatedMemory{
+		backend:   b,
+		busOffset: busOffset,
+		busAddr:   busAddr,
+		size:      size,
+		data:      make([]uint32, (size+3)/4),
+	}
+	b.regions = append(b.regions, m)
+	return m
+}
+
+//findRegion returns the region and offset which contain busAddr.
+func (b *SimulatedBackend) findRegion(busAddr uint32) (*simulatedMemory, uint32, bool) {
+	for _, curRegion := range b.regions {
+		if busAddr >= curRegion.busAddr && busAddr-curRegion.busAddr < curRegion.size {
+			return curRegion, busAddr - curRegion.busAddr, true
+		}
+	}
+	return nil, 0, false
+}
+
+//busRead reads a word by bus address like the DMA engine would.
+func (b *SimulatedBackend) busRead(busAddr uint32) uint32 {
+	m, offset, ok := b.findRegion(busAddr)
+	if !ok {
+		return 0
+	}
+	return m.read(offset)
+}
+
+//busWrite writes a word by bus address like the DMA engine would.
+func (b *SimulatedBackend) busWrite(busAddr uint32, val uint32) {
+	m, offset, ok := b.findRegion(busAddr)
+	if !ok {
+		return
+	}
+	m.write(offset, val)
+}
+
+//setStallDMA sets if DMA transfers end. Used to test the render timeout
+func (b *SimulatedBackend) setStallDMA(stall bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stallDMA = stall
+}
+
+//runDMA processes all control blocks of channel starting with the one at cbAddress.
+func (b *SimulatedBackend) runDMA(channel uint32, cbAddress uint32) {
+	var transferred []uint32
+	for i := 0; cbAddress != 0 && i < simulatedMaxControlBlocks; i++ {
+		ti := b.busRead(cbAddress + registerOffsetDmaCBTi)
+		src := b.busRead(cbAddress + registerOffsetDmaCBSrcAddress)
+		dest := b.busRead(cbAddress + registerOffsetDmaCBDestAddress)
+		length := b.busRead(cbAddress + registerOffsetDmaCBTransferLength)
+		for j := uint32(0); j < length/4; j++ {
+			val := b.busRead(src)
+			b.busWrite(dest, val)
+			transferred = append(transferred, val)
+			if ti&registerValueDmaCBTiSrcInc != 0 {
+				src += 4
+			}
+			if ti&registerValueDmaCBTiDestInc != 0 {
+				dest += 4
+			}
+		}
+		cbAddress = b.busRead(cbAddress + registerOffsetDmaCBNextCBAddress)
+	}
+	b.lastTransfer[channel] = transferred
+}
+
+//simulatedMemory is a region of simulated registers or memory.
+type simulatedMemory struct {
+	backend   *SimulatedBackend
+	busOffset uint32
+	busAddr   uint32
+	size      uint32
+	data      []uint32
+}
+
+func (m *simulatedMemory) String() string {
+	var res string
+	res += fmt.Sprintln("Simulated memory")
+	res += fmt.Sprintf("Size %d bytes\n", m.size)
+	res += fmt.Sprintf("BusAddr %x\n", m.busAddr)
+	return res
+}
+
+func (m *simulatedMemory) Read32(offset uint32) uint32 {
+	m.backend.mu.Lock()
+	defer m.backend.mu.Unlock()
+	return m.read(offset)
+}
+
+func (m *simulatedMemory) Write32(offset uint32, val uint32) {
+	m.backend.mu.Lock()
+	defer m.backend.mu.Unlock()
+	m.write(offset, val)
+}
+
+func (m *simulatedMemory) BusAddr() uint32 {
+	return m.busAddr
+}
+
+func (m *simulatedMemory) Size() uint32 {
+	return m.size
+}
+
+func (m *simulatedMemory) Unmap() error {
+	m.backend.mu.Lock()
+	defer m.backend.mu.Unlock()
+	for i, curRegion := range m.backend.regions {
+		if curRegion == m {
+			m.backend.regions = append(m.backend.regions[:i], m.backend.regions[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (m *simulatedMemory) read(offset uint32) uint32 {
+	if offset/4 >= uint32(len(m.data)) {
+		return 0
+	}
+	return m.data[offset/4]
+}
+
+func (m *simulatedMemory) write(offset uint32, val uint32) {
+	if offset/4 >= uint32(len(m.data)) {
+		return
+	}
+	switch m.busOffset {
+	case registerClockBusOffset:
+		val = m.writeClock(offset, val)
+	case registerPWMBusOffset:
+		val = m.writePWM(offset, val)
+	case registerPCMBusOffset:
+		val = m.writePCM(offset, val)
+	case registerDMABusOffset:
+		val = m.writeDMA(offset, val)
+	}
+	m.data[offset/4] = val
+}
+
+//writeClock models the clock manager and returns the value to store.
+func (m *simulatedMemory) writeClock(offset uint32, val uint32) uint32 {
+	old := m.read(offset)
+	if val&0xff000000 != registerValueClkPasswd {
+		//Writes without password are ignored
+		return old
+	}
+	val &= 0x00ffffff
+	if offset%8 != 0 {
+		//Divisor register
+		return val
+	}
+	val &= ^registerValueClkCtlBusy
+	if val&registerValueClkCtlKill != 0 {
+		return val & ^(registerValueClkCtlKill | registerValueClkCtlEnab)
+	}
+	if val&registerValueClkCtlEnab != 0 {
+		val |= registerValueClkCtlBusy
+	}
+	return val
+}
+
+//writePWM models the PWM peripheral and returns the value to store.
+func (m *simulatedMemory) writePWM(offset uint32, val uint32) uint32 {
+	switch offset {
+	case registerOffsetPWMCtl:
+		if val&registerValuePWMCtlClrf1 != 0 {
+			m.backend.pwmFIFO = nil
+		}
+		return val & ^registerValuePWMCtlClrf1
+	case registerOffsetPWMFif1:
+		m.backend.pwmFIFO = append(m.backend.pwmFIFO, val)
+		return 0
+	}
+	return val
+}
+
+//writePCM models the PCM peripheral and returns the value to store.
+func (m *simulatedMemory) writePCM(offset uint32, val uint32) uint32 {
+	switch offset {
+	case registerOffsetPCMCs:
+		if val&registerValuePCMCsTxclr != 0 {
+			m.backend.pcmFIFO = nil
+		}
+		return val & ^registerValuePCMCsTxclr
+	case registerOffsetPCMFifo:
+		m.backend.pcmFIFO = append(m.backend.pcmFIFO, val)
+		return 0
+	}
+	return val
+}
+
+//writeDMA models the DMA controller and returns the value to store.
+func (m *simulatedMemory) writeDMA(offset uint32, val uint32) uint32 {
+	if offset >= registerOffsetDmaEnable || offset%0x100 != registerOffsetDmaCs {
+		return val
+	}
+	channel := offset / 0x100
+	if val&registerValueDmaCsReset != 0 {
+		for i := offset; i < offset+0x100 && i/4 < uint32(len(m.data)); i += 4 {
+			m.data[i/4] = 0
+		}
+		return 0
+	}
+	old := m.read(offset)
+	statusBits := registerValueDmaCsEnd | registerValueDmaCsInt
+	res := (old & statusBits & ^val) | (val & ^statusBits) //END and INT are cleared by writing 1
+	if res&registerValueDmaCsActive == 0 || m.read(registerOffsetDmaEnable)&(1<<channel) == 0 {
+		return res
+	}
+	if m.backend.stallDMA {
+		return res
+	}
+	cbAddress := m.read(registerOffsetDmaChannel(channel, registerOffsetDmaConblkAd))
+	ti := m.backend.busRead(cbAddress + registerOffsetDmaCBTi)
+	m.backend.runDMA(channel, cbAddress)
+	m.data[registerOffsetDmaChannel(channel, registerOffsetDmaConblkAd)/4] = 0
+	res = (res & ^registerValueDmaCsActive) | registerValueDmaCsEnd
+	if ti&registerValueDmaCBTiInten != 0 {
+		res |= registerValueDmaCsInt
+	}
+	return res
+}
+
+//simulatedSPI is a SPIDevice of the SimulatedBackend
+type simulatedSPI struct {
+	backend *SimulatedBackend
+}
+
+func (s *simulatedSPI) Transfer(data []byte) error {
+	s.backend.mu.Lock()
+	defer s.backend.mu.Unlock()
+	s.backend.spiData = append([]byte(nil), data...)
+	return nil
+}
+
+func (s *simulatedSPI) MaxTransferSize() int {
+	return 0
+}
+
+func (s *simulatedSPI) Close() error {
+	return nil
+}
@@ -0,0 +1,52 @@
+package rpiws281x
+
+import (
+	"image/color"
+
+	"github.com/pkg/errors"
+)
+
+//Strip returns the LEDs and the StripType set with SetStrip for the strip with index stripIndex.
+func (c *Config) Strip(stripIndex int) (LEDs, StripType, error) {
+	if stripIndex < 0 || stripIndex >= len(c.channels) {
+		return nil, 0, errors.Wrap(ErrConfigWrongIndex, "config Strip")
+	}
+	if !c.channels[stripIndex].active {
+		return nil, 0, errors.Wrap(ErrNoActiveChannel, "config Strip")
+	}
+	return c.channels[stripIndex].strip, c.channels[stripIndex].stripType, nil
+}
+
+//SetSlots sets the LEDs of leds starting at position from slots as received by DMX like protocols.
+//Each LED takes colorCount (3 or 4) slots in the order red, green, blue and white. Incomplete LEDs at the end of slots
+//and LEDs after the end of leds are skipped. Returns the number of LEDs set.
+func SetSlots(leds WritableLEDs, position int, slots []byte, colorCount int) int {
+	if colorCount != 4 {
+		colorCount = 3
+	}
+	count := len(slots) / colorCount
+	if position < 0 {
+		return 0
+	}
+	if position+count > leds.TotalCount() {
+		count = leds.TotalCount() - position
+	}
+	if count <= 0 {
+		return 0
+	}
+	ledStrip, isStrip := leds.(*LEDStrip)
+	for i := 0; i < count; i++ {
+		curSlots := slots[i*colorCount : (i+1)*colorCount]
+		var white uint8
+		if colorCount == 4 {
+			white = curSlots[3]
+		}
+		if isStrip {
+			ledStrip.leds[position+i] = uint32(white)<<24 | uint32(curSlots[0])<<16 | uint32(curSlots[1])<<8 | uint32(curSlots[2])
+			continue
+		}
+		//The alpha value is the white value for SetColor
+		leds.SetColor(position+i, color.RGBA{curSlots[0], curSlots[1], curSlots[2], white})
+	}
+	return count
+}
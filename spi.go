@@ -0,0 +1,110 @@
+package rpiws281x
+
+import (
+	"fmt"
+
+	"github.com/DerLukas15/rpigpio"
+	"github.com/pkg/errors"
+)
+
+/*
+ * Pin map of configuration for SPI (SPI0 MOSI)
+ * GPIO    SPI
+ *  10      0
+ *
+ * The pin mode is set by the kernel driver.
+ */
+
+const spiDevicePrefix = "/dev/spidev0." // Devices of SPI0. MOSI is GPIO 10
+
+var spiDev SPIDevice
+var spiDataMem bufferMemory
+var spiData []byte // Bytes of spiDataMem in send order. Reused for every frame
+
+var (
+	pin10, _    = rpigpio.NewPin(10)
+	spiChannels = channelPinTable{
+		pinTable{ // Mapping of Pin to alternate function for SPI
+			{
+				pinNum:  pin10,
+				altMode: rpigpio.ModeAlternate0,
+			},
+		},
+	}
+)
+
+//opens the spi device and allocates the data storage
+func initializeSPI(channels []ledChannel, timing *symbolTiming, device string) error {
+	var err error
+	logOutput("Opening SPI device " + device)
+	spiDev, err = activeBackend.OpenSPI(device, timing.bitRate)
+	if err != nil {
+		return errors.Wrap(err, "SPI init")
+	}
+	logOutput("Done SPI device")
+
+	curChannel := &channels[0]
+	ledBitCount := curChannel.strip.TotalCount() * ledColorCount(curChannel.stripType) * 8 * timing.format.Bits // Each LED has 8 Bit per Color which are each mapped to a symbol
+	dataSize := (uint32(ledBitCount>>3) & ^uint32(0x7)) + 8
+	dataSize += 32 //Spacing so that leds render. Same as for PWM
+	if maxSize := spiDev.MaxTransferSize(); maxSize > 0 && int(dataSize) > maxSize {
+		return errors.Wrapf(ErrSPIFrameTooLarge, "SPI init: %d bytes needed, spidev bufsiz is %d", dataSize, maxSize)
+	}
+	logOutput(fmt.Sprintf("Initializing SPI data storage. size: %d bytes", dataSize))
+	spiDataMem = make(bufferMemory, dataSize/4)
+	spiData = make([]byte, dataSize)
+	//Inversion is done by software for SPI. The idle line has to be high in this case.
+	if curChannel.invert {
+		for i := range spiDataMem {
+			spiDataMem[i] = 0xffffffff
+		}
+	}
+	return nil
+}
+
+//closes the spi device
+func cleanupSPI() error {
+	spiDataMem = nil
+	spiData = nil
+	if spiDev == nil {
+		return nil
+	}
+	err := spiDev.Close()
+	if err != nil {
+		return errors.Wrap(err, "cleanup spi")
+	}
+	spiDev = nil
+	return nil
+}
+
+//outputs signals with SPI for the given channels. Returns once the data is sent.
+func renderSPI(channels []ledChannel, timing *symbolTiming) (int64, error) {
+	curChannel := &channels[0]
+	if !curChannel.active {
+		return 0, nil
+	}
+	// SPI has no hardware inversion
+	encodeChannel(curChannel, timing, curChannel.invert)
+	return writeSPI(channels, timing)
+}
+
+//sends the encoded data of the channel. Returns once the data is sent.
+func writeSPI(channels []ledChannel, timing *symbolTiming) (int64, error) {
+	curChannel := &channels[0]
+	if !curChannel.active {
+		return 0, nil
+	}
+	writeWords(spiDataMem, curChannel.encoded, 0, 1)
+	//SPI sends the bytes in memory order. Most significant byte of each word first.
+	for i, curWord := range spiDataMem {
+		spiData[i*4] = byte(curWord >> 24)
+		spiData[i*4+1] = byte(curWord >> 16)
+		spiData[i*4+2] = byte(curWord >> 8)
+		spiData[i*4+3] = byte(curWord)
+	}
+	err := spiDev.Transfer(spiData)
+	if err != nil {
+		return 0, errors.Wrap(err, "render spi")
+	}
+	return int64(timing.reset), nil
+}
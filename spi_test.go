@@ -0,0 +1,92 @@
+package rpiws281x
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/DerLukas15/rpigpio"
+	"github.com/pkg/errors"
+)
+
+func TestSPIInitializeRenderStop(t *testing.T) {
+	backend := NewSimulatedBackend()
+	err := SetBackend(backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := New(DriverSPI)
+	strip := testStrip(6, true)
+	err = c.SetStrip(strip, 10, SK6812StripGRBW, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetBrightness(255, 0)
+	err = c.Initialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+	for frame := 0; frame < 2; frame++ {
+		strip.SetDirect(0, uint32(frame)*0x01010101)
+		err = c.Render(-1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := backend.SPIData()
+		if len(data) != len(spiDataMem)*4 {
+			t.Fatalf("got %d bytes want %d", len(data), len(spiDataMem)*4)
+		}
+		//SPI sends the most significant byte of each word first
+		words := make([]uint32, len(data)/4)
+		for i := range words {
+			words[i] = binary.BigEndian.Uint32(data[i*4:])
+		}
+		decoded, err := DecodePWM(words, []PWMChannelLayout{{
+			LEDCount:  strip.TotalCount(),
+			StripType: SK6812StripGRBW,
+			Symbols:   c.symbols.format,
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkDecoded(t, decoded, strip)
+	}
+
+	err = c.Stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spiDev != nil || spiDataMem != nil || spiData != nil {
+		t.Error("SPI device not closed")
+	}
+	//The pin is handled by the kernel driver and never touched
+	checkPin(t, backend, 10, rpigpio.ModeIn, 0)
+	err = c.Render(-1)
+	if err == nil {
+		t.Error("Render after Stop succeeded")
+	}
+}
+
+func TestSetSPIDevice(t *testing.T) {
+	tests := []struct {
+		device string
+		ok     bool
+	}{
+		{"/dev/spidev0.0", true},
+		{"/dev/spidev0.1", true},
+		//MOSI of SPI1 is not GPIO 10
+		{"/dev/spidev1.0", false},
+		{"/dev/spidev0.", false},
+		{"", false},
+	}
+	for _, test := range tests {
+		c, _ := New(DriverSPI)
+		err := c.SetSPIDevice(test.device)
+		if (err == nil) != test.ok {
+			t.Errorf("%q: got %v", test.device, err)
+		}
+		if !test.ok && errors.Cause(err) != ErrWrongSPIDevice {
+			t.Errorf("%q: got %v want %v", test.device, err, ErrWrongSPIDevice)
+		}
+	}
+}
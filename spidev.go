@@ -0,0 +1,144 @@
+package rpiws281x
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+//ioctl requests of spidev. See linux/spi/spidev.h
+const (
+	spiIocMagic               = 'k'
+	spiIocWrMode              = (1 << 30) | (1 << 16) | (spiIocMagic << 8) | 1  // _IOW(SPI_IOC_MAGIC, 1, __u8)
+	spiIocWrBitsPerWord       = (1 << 30) | (1 << 16) | (spiIocMagic << 8) | 3  // _IOW(SPI_IOC_MAGIC, 3, __u8)
+	spiIocWrMaxSpeedHz        = (1 << 30) | (4 << 16) | (spiIocMagic << 8) | 4  // _IOW(SPI_IOC_MAGIC, 4, __u32)
+	spiIocMessageOne          = (1 << 30) | (32 << 16) | (spiIocMagic << 8) | 0 // SPI_IOC_MESSAGE(1)
+	spiBitsPerWord      uint8 = 8
+	spidevBufsizPath          = "/sys/module/spidev/parameters/bufsiz"
+	spidevDefaultBufsiz       = 4096 // Default of the bufsiz parameter
+)
+
+//spiIocTransfer is struct spi_ioc_transfer
+type spiIocTransfer struct {
+	txBuf          uint64
+	rxBuf          uint64
+	len            uint32
+	speedHz        uint32
+	delayUsecs     uint16
+	bitsPerWord    uint8
+	csChange       uint8
+	txNbits        uint8
+	rxNbits        uint8
+	wordDelayUsecs uint8
+	pad            uint8
+}
+
+//spidev is a SPIDevice using the spidev kernel driver.
+//If the device is not a character device (i.e. a normal file for testing), all data is written to it without ioctls.
+type spidev struct {
+	file    *os.File
+	speedHz uint32
+	isChar  bool
+	bufsiz  int // Largest message accepted by the kernel driver
+}
+
+//opens and configures a spidev device
+func openSpidev(device string, speedHz uint32) (*spidev, error) {
+	file, err := os.OpenFile(device, os.O_RDWR, 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "open spidev")
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, errors.Wrap(err, "open spidev")
+	}
+	s := &spidev{
+		file:    file,
+		speedHz: speedHz,
+		isChar:  info.Mode()&os.ModeCharDevice != 0,
+	}
+	if !s.isChar {
+		return s, nil
+	}
+	s.bufsiz = readSpidevBufsiz(spidevBufsizPath)
+	var mode uint8 //Mode 0
+	bitsPerWord := spiBitsPerWord
+	err = s.ioctl(spiIocWrMode, unsafe.Pointer(&mode))
+	if err != nil {
+		file.Close()
+		return nil, errors.Wrap(err, "spidev mode")
+	}
+	err = s.ioctl(spiIocWrBitsPerWord, unsafe.Pointer(&bitsPerWord))
+	if err != nil {
+		file.Close()
+		return nil, errors.Wrap(err, "spidev bits per word")
+	}
+	err = s.ioctl(spiIocWrMaxSpeedHz, unsafe.Pointer(&speedHz))
+	if err != nil {
+		file.Close()
+		return nil, errors.Wrap(err, "spidev speed")
+	}
+	return s, nil
+}
+
+func (s *spidev) ioctl(request uintptr, arg unsafe.Pointer) error {
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, s.file.Fd(), request, uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+//returns the bufsiz parameter of the spidev module read from path. The default is returned if it can't be read.
+func readSpidevBufsiz(path string) int {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return spidevDefaultBufsiz
+	}
+	bufsiz, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil || bufsiz <= 0 {
+		return spidevDefaultBufsiz
+	}
+	return bufsiz
+}
+
+//Transfer sends data in one spi message.
+//The size is limited by the bufsiz parameter of the spidev module (default 4096 bytes). See MaxTransferSize.
+func (s *spidev) Transfer(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if !s.isChar {
+		_, err := s.file.WriteAt(data, 0)
+		return err
+	}
+	transfer := spiIocTransfer{
+		txBuf:       uint64(uintptr(unsafe.Pointer(&data[0]))),
+		len:         uint32(len(data)),
+		speedHz:     s.speedHz,
+		bitsPerWord: spiBitsPerWord,
+	}
+	err := s.ioctl(spiIocMessageOne, unsafe.Pointer(&transfer))
+	runtime.KeepAlive(data)
+	if err != nil {
+		return errors.Wrap(err, "spidev transfer")
+	}
+	return nil
+}
+
+//MaxTransferSize returns the bufsiz parameter of the spidev module. It can be raised with spidev.bufsiz=<bytes>
+//in /boot/cmdline.txt. 0 is returned for a normal file.
+func (s *spidev) MaxTransferSize() int {
+	return s.bufsiz
+}
+
+//Close closes the device.
+func (s *spidev) Close() error {
+	return s.file.Close()
+}
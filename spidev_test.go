@@ -0,0 +1,114 @@
+package rpiws281x
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestSpidevFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spidev")
+	err := os.WriteFile(path, nil, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev, err := openSpidev(path, 2400000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dev.Close()
+	if dev.MaxTransferSize() != 0 {
+		t.Errorf("got max transfer size %d for a file", dev.MaxTransferSize())
+	}
+	for _, data := range [][]byte{{1, 2, 3, 4, 5, 6}, {7, 8, 9, 10, 11, 12}} {
+		err = dev.Transfer(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		written, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(written, data) {
+			t.Errorf("got %v want %v", written, data)
+		}
+	}
+}
+
+func TestReadSpidevBufsiz(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		content string
+		want    int
+	}{
+		{"65536\n", 65536},
+		{"garbage\n", spidevDefaultBufsiz},
+		{"0\n", spidevDefaultBufsiz},
+	}
+	for i, test := range tests {
+		path := filepath.Join(dir, "bufsiz")
+		err := os.WriteFile(path, []byte(test.content), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := readSpidevBufsiz(path); got != test.want {
+			t.Errorf("%d: got %d want %d", i, got, test.want)
+		}
+	}
+	if got := readSpidevBufsiz(filepath.Join(dir, "missing")); got != spidevDefaultBufsiz {
+		t.Errorf("missing file: got %d want %d", got, spidevDefaultBufsiz)
+	}
+}
+
+//limitedSPIBackend opens SPI devices with a transfer size limit
+type limitedSPIBackend struct {
+	*SimulatedBackend
+	limit int
+}
+
+type limitedSPI struct {
+	SPIDevice
+	limit int
+}
+
+func (b limitedSPIBackend) OpenSPI(device string, speedHz uint32) (SPIDevice, error) {
+	dev, err := b.SimulatedBackend.OpenSPI(device, speedHz)
+	return limitedSPI{SPIDevice: dev, limit: b.limit}, err
+}
+
+func (s limitedSPI) MaxTransferSize() int {
+	return s.limit
+}
+
+func TestInitializeSPIFrameTooLarge(t *testing.T) {
+	err := SetBackend(limitedSPIBackend{SimulatedBackend: NewSimulatedBackend(), limit: 4096})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer SetBackend(NewSimulatedBackend())
+	c, _ := New(DriverSPI)
+	//9 bytes per LED with 3 bit symbols
+	err = c.SetStrip(NewLEDStrip(500), 10, WS2812Strip, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Initialize()
+	if errors.Cause(err) != ErrSPIFrameTooLarge {
+		c.Stop()
+		t.Fatalf("got %v want %v", err, ErrSPIFrameTooLarge)
+	}
+	//A failed Initialize must not block the driver
+	c, _ = New(DriverSPI)
+	c.SetStrip(NewLEDStrip(100), 10, WS2812Strip, 0, false)
+	err = c.Initialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
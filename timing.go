@@ -0,0 +1,137 @@
+package rpiws281x
+
+import (
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+//TimingProfile describes the signal timing of an LED chip. Each bit starts high for T0H (bit 0) or T1H (bit 1)
+//and stays low for the rest of Period. After the last LED the line has to be low for Reset to latch the data.
+type TimingProfile struct {
+	T0H    time.Duration
+	T1H    time.Duration
+	Period time.Duration
+	Reset  time.Duration
+}
+
+//Timing profiles of common chips with typical values of their datasheets
+var (
+	TimingWS2812 = TimingProfile{T0H: 400 * time.Nanosecond, T1H: 800 * time.Nanosecond, Period: 1250 * time.Nanosecond, Reset: 300 * time.Microsecond}  // WS2812 and WS2812B. Default
+	TimingWS2811 = TimingProfile{T0H: 500 * time.Nanosecond, T1H: 1200 * time.Nanosecond, Period: 2500 * time.Nanosecond, Reset: 300 * time.Microsecond} // WS2811 in low speed mode
+	TimingWS2813 = TimingProfile{T0H: 300 * time.Nanosecond, T1H: 750 * time.Nanosecond, Period: 1250 * time.Nanosecond, Reset: 300 * time.Microsecond}
+	TimingWS2815 = TimingProfile{T0H: 300 * time.Nanosecond, T1H: 1000 * time.Nanosecond, Period: 1360 * time.Nanosecond, Reset: 300 * time.Microsecond}
+	TimingSK6812 = TimingProfile{T0H: 300 * time.Nanosecond, T1H: 600 * time.Nanosecond, Period: 1250 * time.Nanosecond, Reset: 80 * time.Microsecond} // SK6812 and SK6812RGBW
+	TimingTM1814 = TimingProfile{T0H: 360 * time.Nanosecond, T1H: 720 * time.Nanosecond, Period: 1250 * time.Nanosecond, Reset: 200 * time.Microsecond}
+	TimingGS8208 = TimingProfile{T0H: 300 * time.Nanosecond, T1H: 800 * time.Nanosecond, Period: 1250 * time.Nanosecond, Reset: 300 * time.Microsecond}
+)
+
+const (
+	minSymbolBits   = 3                     // Needed for a high and low part of both bits
+	maxSymbolBits   = 8                     // Half of the symbols of one color fit into 32 bits
+	timingTolerance = 150 * time.Nanosecond // Allowed deviation of T0H, T1H and Period
+	maxClockDivisor = 0xfff                 // Integer part of the clock divisor
+)
+
+//SymbolFormat describes how one bit is sent. Each bit is sent as a symbol of Bits output bits. The first ZeroHigh
+//(bit 0) or OneHigh (bit 1) output bits are high, the rest is low.
+type SymbolFormat struct {
+	Bits     int
+	ZeroHigh int
+	OneHigh  int
+}
+
+//DefaultSymbols is the format of TimingWS2812 and the format assumed for an empty SymbolFormat.
+var DefaultSymbols = SymbolFormat{Bits: 3, ZeroHigh: 1, OneHigh: 2}
+
+//returns the output bits of the symbol for bit 0 or 1
+func (f SymbolFormat) symbol(bit uint8) uint64 {
+	high := f.ZeroHigh
+	if bit != 0 {
+		high = f.OneHigh
+	}
+	return (uint64(1)<<high - 1) << (f.Bits - high)
+}
+
+//symbolTiming is a TimingProfile converted for the hardware
+type symbolTiming struct {
+	format  SymbolFormat
+	divisor uint32      // Clock divisor. 0 if the clock is not set by this package (SPI)
+	bitRate uint32      // Output bits per second
+	bitTime float64     // Time of one LED bit in microseconds
+	reset   uint32      // Reset time in microseconds
+	table   [256]uint64 // Output bits for each color value. The most significant bit is sent first
+}
+
+//SetTiming sets the timing profile of the connected LEDs. The symbols and the clock are derived from the profile during Initialize.
+//Default is TimingWS2812.
+func (c *Config) SetTiming(profile TimingProfile) error {
+	if c.initialized {
+		return errors.Wrap(ErrConfigInitialized, "config SetTiming")
+	}
+	if profile.T0H <= 0 || profile.T1H <= profile.T0H || profile.Period <= profile.T1H || profile.Reset < 0 {
+		return errors.Wrap(ErrWrongTiming, "config SetTiming")
+	}
+	c.timing = profile
+	return nil
+}
+
+//Timing returns the timing profile of the Config.
+func (c *Config) Timing() TimingProfile {
+	return c.timing
+}
+
+//derives the symbols for profile. oscFreq is the frequency of the clock source in Hz or 0 if any bit rate is possible.
+//The smallest number of bits per symbol which keeps T0H and T1H within the tolerance is used.
+func newSymbolTiming(profile TimingProfile, oscFreq uint32) (*symbolTiming, error) {
+	for bits := minSymbolBits; bits <= maxSymbolBits; bits++ {
+		//Duration of one output bit
+		outputBit := float64(profile.Period) / float64(bits)
+		var divisor uint32
+		if oscFreq != 0 {
+			divisor = uint32(math.Round(float64(oscFreq) * outputBit / float64(time.Second)))
+			if divisor < 1 || divisor > maxClockDivisor {
+				continue
+			}
+			outputBit = float64(divisor) * float64(time.Second) / float64(oscFreq)
+		}
+		zeroHigh := int(math.Round(float64(profile.T0H) / outputBit))
+		oneHigh := int(math.Round(float64(profile.T1H) / outputBit))
+		if zeroHigh < 1 || oneHigh <= zeroHigh || oneHigh >= bits {
+			continue
+		}
+		if math.Abs(float64(zeroHigh)*outputBit-float64(profile.T0H)) > float64(timingTolerance) ||
+			math.Abs(float64(oneHigh)*outputBit-float64(profile.T1H)) > float64(timingTolerance) ||
+			math.Abs(float64(bits)*outputBit-float64(profile.Period)) > float64(timingTolerance) {
+			continue
+		}
+		res := &symbolTiming{
+			format:  SymbolFormat{Bits: bits, ZeroHigh: zeroHigh, OneHigh: oneHigh},
+			divisor: divisor,
+			bitRate: uint32(math.Round(float64(time.Second) / outputBit)),
+			bitTime: outputBit * float64(bits) / float64(time.Microsecond),
+			reset:   uint32(profile.Reset / time.Microsecond),
+		}
+		for value := 0; value < 256; value++ {
+			var pattern uint64
+			for k := 7; k >= 0; k-- { // Bit per Color
+				pattern = pattern<<bits | res.format.symbol(uint8(value>>k)&1)
+			}
+			res.table[value] = pattern
+		}
+		return res, nil
+	}
+	return nil, errors.Wrap(ErrWrongTiming, "no symbols fit the timing")
+}
+
+//returns the profile which was used before timing profiles with the frequency of the LED bits in Hz
+func frequencyTiming(frequency uint32) TimingProfile {
+	period := time.Second / time.Duration(frequency)
+	return TimingProfile{
+		T0H:    period / 3,
+		T1H:    2 * period / 3,
+		Period: period,
+		Reset:  300 * time.Microsecond,
+	}
+}
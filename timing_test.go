@@ -0,0 +1,179 @@
+package rpiws281x
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestSymbolTimingPresets(t *testing.T) {
+	tests := []struct {
+		name        string
+		profile     TimingProfile
+		format      SymbolFormat
+		divisor     uint32
+		spiBitRate  uint32 // Bit rate without a clock divisor
+		resetMicros uint32
+	}{
+		{"WS2812", TimingWS2812, SymbolFormat{3, 1, 2}, 8, 2400000, 300},
+		{"WS2811", TimingWS2811, SymbolFormat{4, 1, 2}, 12, 1600000, 300},
+		{"WS2813", TimingWS2813, SymbolFormat{3, 1, 2}, 8, 2400000, 300},
+		{"WS2815", TimingWS2815, SymbolFormat{4, 1, 3}, 7, 2941176, 300},
+		{"SK6812", TimingSK6812, SymbolFormat{4, 1, 2}, 6, 3200000, 80},
+		{"TM1814", TimingTM1814, SymbolFormat{3, 1, 2}, 8, 2400000, 200},
+		{"GS8208", TimingGS8208, SymbolFormat{3, 1, 2}, 8, 2400000, 300},
+	}
+	for _, test := range tests {
+		timing, err := newSymbolTiming(test.profile, simulatedHardware.OscFreq)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if timing.format != test.format || timing.divisor != test.divisor || timing.reset != test.resetMicros {
+			t.Errorf("%s: got %+v divisor %d reset %d want %+v divisor %d reset %d", test.name, timing.format, timing.divisor,
+				timing.reset, test.format, test.divisor, test.resetMicros)
+		}
+		if want := simulatedHardware.OscFreq / test.divisor; timing.bitRate != want {
+			t.Errorf("%s: got bit rate %d want %d", test.name, timing.bitRate, want)
+		}
+		//The derived symbols stay within the tolerance
+		outputBit := time.Second / time.Duration(timing.bitRate)
+		for _, curPart := range [][2]time.Duration{
+			{time.Duration(timing.format.ZeroHigh) * outputBit, test.profile.T0H},
+			{time.Duration(timing.format.OneHigh) * outputBit, test.profile.T1H},
+			{time.Duration(timing.format.Bits) * outputBit, test.profile.Period},
+		} {
+			if diff := curPart[0] - curPart[1]; diff > timingTolerance || diff < -timingTolerance {
+				t.Errorf("%s: got %v want %v", test.name, curPart[0], curPart[1])
+			}
+		}
+		//Without a clock divisor (SPI) the bit rate follows the period
+		timing, err = newSymbolTiming(test.profile, 0)
+		if err != nil {
+			t.Errorf("%s without divisor: %v", test.name, err)
+			continue
+		}
+		if timing.format != test.format || timing.divisor != 0 || timing.bitRate != test.spiBitRate {
+			t.Errorf("%s without divisor: got %+v divisor %d bit rate %d want %+v divisor 0 bit rate %d", test.name, timing.format,
+				timing.divisor, timing.bitRate, test.format, test.spiBitRate)
+		}
+	}
+}
+
+func TestSymbolTimingRejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile TimingProfile
+		oscFreq uint32
+	}{
+		//Any number of bits misses T0H or T1H by more than timingTolerance
+		{"outside tolerance", TimingProfile{T0H: 4 * time.Microsecond, T1H: 10 * time.Microsecond, Period: 20 * time.Microsecond}, 0},
+		//T0H and T1H would need more than maxSymbolBits to be told apart
+		{"too many bits", TimingProfile{T0H: 400 * time.Nanosecond, T1H: 420 * time.Nanosecond, Period: 1250 * time.Nanosecond}, 0},
+		{"too short high time", TimingProfile{T0H: 20 * time.Nanosecond, T1H: 1000 * time.Nanosecond, Period: 2000 * time.Nanosecond}, 0},
+		{"divisor too large", TimingProfile{T0H: 500 * time.Microsecond, T1H: 1000 * time.Microsecond, Period: 2000 * time.Microsecond},
+			simulatedHardware.OscFreq},
+	}
+	for _, test := range tests {
+		_, err := newSymbolTiming(test.profile, test.oscFreq)
+		if errors.Cause(err) != ErrWrongTiming {
+			t.Errorf("%s: got %v want %v", test.name, err, ErrWrongTiming)
+		}
+	}
+	//3 bits miss T0H by 167 ns
+	timing, err := newSymbolTiming(TimingProfile{T0H: 500 * time.Nanosecond, T1H: 1000 * time.Nanosecond, Period: 2000 * time.Nanosecond}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timing.format != (SymbolFormat{4, 1, 2}) {
+		t.Errorf("got %+v want 4 bits", timing.format)
+	}
+}
+
+func TestSetTiming(t *testing.T) {
+	c, _ := New(DriverPWM)
+	for _, profile := range []TimingProfile{
+		{T0H: 0, T1H: 800, Period: 1250},
+		{T0H: 400, T1H: 400, Period: 1250},
+		{T0H: 400, T1H: 800, Period: 800},
+		{T0H: 400, T1H: 800, Period: 1250, Reset: -1},
+	} {
+		err := c.SetTiming(profile)
+		if errors.Cause(err) != ErrWrongTiming {
+			t.Errorf("%+v: got %v want %v", profile, err, ErrWrongTiming)
+		}
+	}
+	if c.Timing() != TimingWS2812 {
+		t.Errorf("got %+v want the default %+v", c.Timing(), TimingWS2812)
+	}
+
+	//Rejected by Initialize as no symbols fit
+	err := SetBackend(NewSimulatedBackend())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.SetStrip(NewLEDStrip(1), 18, WS2812Strip, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.SetTiming(TimingProfile{T0H: 400 * time.Nanosecond, T1H: 420 * time.Nanosecond, Period: 1250 * time.Nanosecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Initialize()
+	if errors.Cause(err) != ErrWrongTiming {
+		c.Stop()
+		t.Errorf("Initialize: got %v want %v", err, ErrWrongTiming)
+	}
+}
+
+func TestRenderTimingProfiles(t *testing.T) {
+	tests := []struct {
+		name      string
+		profile   TimingProfile
+		stripType StripType
+	}{
+		{"SK6812", TimingSK6812, SK6812StripGRBW},
+		{"WS2815", TimingWS2815, WS2812Strip},
+		{"WS2811", TimingWS2811, WS2811StripRGB},
+	}
+	for _, test := range tests {
+		backend := NewSimulatedBackend()
+		err := SetBackend(backend)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, _ := New(DriverPWM)
+		strip := testStrip(5, test.stripType.ColorCount() == 4)
+		err = c.SetStrip(strip, 18, test.stripType, 0, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = c.SetTiming(test.profile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.SetBrightness(255, 0)
+		err = c.Initialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := newSymbolTiming(test.profile, simulatedHardware.OscFreq)
+		checkRegister(t, test.name+" clock DIV", clockRegisterMem, registerOffsetClkPwmDiv, registerValueClkDivDivi(0xfff),
+			registerValueClkDivDivi(want.divisor))
+		layout := c.PWMLayout()
+		if layout[0].Symbols != want.format {
+			t.Errorf("%s: got layout symbols %+v want %+v", test.name, layout[0].Symbols, want.format)
+		}
+		decoded := renderDecoded(t, c, backend)
+		checkDecoded(t, decoded[:1], strip)
+		//Decoding with the default symbols fails or gives other colors
+		layout[0].Symbols = DefaultSymbols
+		decoded, err = DecodePWM(backend.LastTransfer(c.dmaChannel), layout)
+		if err == nil && decoded[0].UInt32(0) == strip.UInt32(0) {
+			t.Errorf("%s: decoded with the default symbols", test.name)
+		}
+		c.Stop()
+	}
+}
@@ -0,0 +1,95 @@
+package rpiws281x
+
+import "github.com/pkg/errors"
+
+//VirtualStripPart maps a range of a virtual strip onto one PWM channel.
+type VirtualStripPart struct {
+	Pin     uint32 // Pin of the channel
+	Offset  int    // Position of the first LED of the range in the virtual strip
+	Count   int    // Number of LEDs on the channel. 0 leaves the channel unused
+	Reverse bool   // The first LED of the channel is the last LED of the range
+	Invert  bool   // Invert the signal of the channel
+}
+
+//SetVirtualStrip sets one strip spanning both PWM channels. parts[0] is mapped onto channel 0 and parts[1] onto channel 1.
+/*
+This way the LEDs can be addressed from 0 to TotalCount()-1 while the physical strip is split into two data lines
+for a higher refresh rate. The ranges may overlap to show the same LEDs on both channels.
+
+Brightness, gamma and color correction are still set per channel with the indexes 0 and 1.
+Only DriverPWM is supported.
+*/
+func (c *Config) SetVirtualStrip(ledStrip LEDs, stripType StripType, parts [2]VirtualStripPart) error {
+	if c.driverType != DriverPWM {
+		return errors.Wrap(ErrDriverNotSupported, "config SetVirtualStrip")
+	}
+	if c.initialized {
+		return errors.Wrap(ErrConfigInitialized, "config SetVirtualStrip")
+	}
+	for _, curPart := range parts {
+		if curPart.Offset < 0 || curPart.Count < 0 || curPart.Offset+curPart.Count > ledStrip.TotalCount() {
+			return errors.Wrap(ErrWrongStripRange, "config SetVirtualStrip")
+		}
+	}
+	for stripIndex, curPart := range parts {
+		if curPart.Count == 0 {
+			continue
+		}
+		window := &stripWindow{
+			leds:    ledStrip,
+			parts:   &parts,
+			offset:  curPart.Offset,
+			count:   curPart.Count,
+			reverse: curPart.Reverse,
+		}
+		err := c.SetStrip(window, curPart.Pin, stripType, stripIndex, curPart.Invert)
+		if err != nil {
+			return errors.Wrap(err, "config SetVirtualStrip")
+		}
+	}
+	return nil
+}
+
+//stripWindow is the range of leds sent on one channel
+type stripWindow struct {
+	leds    LEDs
+	parts   *[2]VirtualStripPart // Shared by the windows of one virtual strip
+	offset  int
+	count   int
+	reverse bool
+}
+
+//returns the position in leds
+func (w *stripWindow) position(position int) int {
+	if position < 0 || position >= w.count {
+		return -1
+	}
+	if w.reverse {
+		return w.offset + w.count - 1 - position
+	}
+	return w.offset + position
+}
+
+func (w *stripWindow) Red(position int) uint8 {
+	return w.leds.Red(w.position(position))
+}
+
+func (w *stripWindow) Green(position int) uint8 {
+	return w.leds.Green(w.position(position))
+}
+
+func (w *stripWindow) Blue(position int) uint8 {
+	return w.leds.Blue(w.position(position))
+}
+
+func (w *stripWindow) White(position int) uint8 {
+	return w.leds.White(w.position(position))
+}
+
+func (w *stripWindow) UInt32(position int) uint32 {
+	return w.leds.UInt32(w.position(position))
+}
+
+func (w *stripWindow) TotalCount() int {
+	return w.count
+}
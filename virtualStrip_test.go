@@ -0,0 +1,81 @@
+package rpiws281x
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestSetVirtualStrip(t *testing.T) {
+	backend := NewSimulatedBackend()
+	err := SetBackend(backend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := New(DriverPWM)
+	strip := testStrip(10, false)
+	//The ranges overlap at the LEDs 4 and 5
+	err = c.SetVirtualStrip(strip, WS2812Strip, [2]VirtualStripPart{
+		{Pin: 18, Offset: 0, Count: 6},
+		{Pin: 13, Offset: 4, Count: 6, Reverse: true, Invert: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetBrightness(255, 0)
+	c.SetBrightness(255, 1)
+	err = c.Initialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Stop()
+	err = c.Render(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*LEDStrip{NewLEDStrip(6), NewLEDStrip(6)}
+	for i := 0; i < 6; i++ {
+		want[0].SetDirect(i, strip.UInt32(i))
+		want[1].SetDirect(i, strip.UInt32(9-i))
+	}
+	decoded, err := DecodePWM(backend.LastTransfer(c.dmaChannel), c.PWMLayout())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkDecoded(t, decoded, want[0], want[1])
+	if decoded[0].TotalCount() != 6 || decoded[1].TotalCount() != 6 {
+		t.Errorf("got %d and %d LEDs want 6 each", decoded[0].TotalCount(), decoded[1].TotalCount())
+	}
+	//Inversion is handled by hardware
+	checkRegister(t, "CTL", pwmRegisterMem, registerOffsetPWMCtl, registerValuePWMCtlPola1|registerValuePWMCtlPola2,
+		registerValuePWMCtlPola2)
+
+	//Changes of the virtual strip show up on both channels
+	strip.SetDirect(5, 0xabcdef)
+	want[0].SetDirect(5, 0xabcdef)
+	want[1].SetDirect(4, 0xabcdef)
+	decoded = renderDecoded(t, c, backend)
+	checkDecoded(t, decoded, want[0], want[1])
+}
+
+func TestSetVirtualStripErrors(t *testing.T) {
+	strip := NewLEDStrip(10)
+	tests := []struct {
+		name   string
+		driver DriverType
+		parts  [2]VirtualStripPart
+		want   error
+	}{
+		{"PCM", DriverPCM, [2]VirtualStripPart{{Pin: 21, Count: 10}}, ErrDriverNotSupported},
+		{"negative offset", DriverPWM, [2]VirtualStripPart{{Pin: 18, Offset: -1, Count: 5}}, ErrWrongStripRange},
+		{"negative count", DriverPWM, [2]VirtualStripPart{{Pin: 18, Count: -1}}, ErrWrongStripRange},
+		{"beyond the strip", DriverPWM, [2]VirtualStripPart{{Pin: 18, Count: 5}, {Pin: 13, Offset: 6, Count: 5}}, ErrWrongStripRange},
+	}
+	for _, test := range tests {
+		c, _ := New(test.driver)
+		err := c.SetVirtualStrip(strip, WS2812Strip, test.parts)
+		if errors.Cause(err) != test.want {
+			t.Errorf("%s: got %v want %v", test.name, err, test.want)
+		}
+	}
+}
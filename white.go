@@ -0,0 +1,96 @@
+package rpiws281x
+
+import (
+	"github.com/pkg/errors"
+)
+
+//WhiteMode defines how the white value is set for strips with 4 colors (i.e. SK6812StripGRBW).
+type WhiteMode uint8
+
+//Valid WhiteModes
+const (
+	WhiteNone       WhiteMode = iota // The white value of the LEDs is sent as is. Default
+	WhiteMin                         // The minimum of red, green and blue is moved to white
+	WhiteAccurate                    // The part of red, green and blue matching the color of the white LEDs is moved to white (see SetWhiteTemperature)
+	WhiteBrightness                  // White is set to the maximum of red, green and blue. The colors stay unchanged
+)
+
+//TemperatureWhiteLED is the default color temperature of white LEDs in Kelvin used by WhiteAccurate.
+const TemperatureWhiteLED uint32 = 4500
+
+//SetWhiteMode sets how the white value of the strip with index stripIndex is set. The white value of the LEDs is ignored
+//for all modes except WhiteNone. Only strips with 4 colors are affected.
+//This method can be called once the Config is initialized.
+func (c *Config) SetWhiteMode(mode WhiteMode, stripIndex int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if stripIndex < 0 || stripIndex >= len(c.channels) {
+		return errors.Wrap(ErrConfigWrongIndex, "config SetWhiteMode")
+	}
+	if mode > WhiteBrightness {
+		return errors.Wrap(ErrWrongWhiteMode, "config SetWhiteMode")
+	}
+	c.channels[stripIndex].whiteMode = mode
+	return nil
+}
+
+//SetWhiteTemperature sets the color temperature of the white LEDs of the strip with index stripIndex in Kelvin.
+//Valid values are between 1000 and 40000. Used by WhiteAccurate.
+//This method can be called once the Config is initialized.
+func (c *Config) SetWhiteTemperature(kelvin uint32, stripIndex int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if stripIndex < 0 || stripIndex >= len(c.channels) {
+		return errors.Wrap(ErrConfigWrongIndex, "config SetWhiteTemperature")
+	}
+	if kelvin < 1000 || kelvin > 40000 {
+		return errors.Wrap(ErrWrongTemperature, "config SetWhiteTemperature")
+	}
+	c.channels[stripIndex].whiteColor = kelvinToCorrection(kelvin)
+	return nil
+}
+
+//returns curLED with the white value set by mode. whiteColor is the color of the white LEDs for WhiteAccurate.
+func extractWhite(curLED uint32, mode WhiteMode, whiteColor ColorCorrection) uint32 {
+	red := (curLED >> 16) & 0xff
+	green := (curLED >> 8) & 0xff
+	blue := curLED & 0xff
+	var white uint32
+	switch mode {
+	case WhiteMin:
+		white = minUInt32(red, minUInt32(green, blue))
+		red -= white
+		green -= white
+		blue -= white
+	case WhiteAccurate:
+		//Largest white value which fits into all colors the white LEDs contain
+		white = 255
+		for _, curPart := range [3][2]uint32{{red, uint32(whiteColor.Red)}, {green, uint32(whiteColor.Green)}, {blue, uint32(whiteColor.Blue)}} {
+			if curPart[1] != 0 {
+				white = minUInt32(white, curPart[0]*255/curPart[1])
+			}
+		}
+		red -= minUInt32(red, white*uint32(whiteColor.Red)/255)
+		green -= minUInt32(green, white*uint32(whiteColor.Green)/255)
+		blue -= minUInt32(blue, white*uint32(whiteColor.Blue)/255)
+	case WhiteBrightness:
+		white = maxUInt32(red, maxUInt32(green, blue))
+	default:
+		return curLED
+	}
+	return white<<24 | red<<16 | green<<8 | blue
+}
+
+func minUInt32(a, b uint32) uint32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxUInt32(a, b uint32) uint32 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,110 @@
+package rpiws281x
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestExtractWhite(t *testing.T) {
+	pureWhite := ColorCorrection{255, 255, 255, 255}
+	warmWhite := ColorCorrection{255, 200, 100, 255}
+	tests := []struct {
+		name       string
+		mode       WhiteMode
+		whiteColor ColorCorrection
+		led        uint32
+		want       uint32
+	}{
+		{"none", WhiteNone, pureWhite, 0xff804020, 0xff804020},
+		{"min", WhiteMin, pureWhite, 0x804020, 0x20602000},
+		{"min ignores white", WhiteMin, pureWhite, 0xff804020, 0x20602000},
+		{"min of white", WhiteMin, pureWhite, 0xffffff, 0xff000000},
+		{"min of black", WhiteMin, pureWhite, 0, 0},
+		{"min of red", WhiteMin, pureWhite, 0xff0000, 0xff0000},
+		{"accurate pure white", WhiteAccurate, pureWhite, 0x804020, 0x20602000},
+		{"accurate white LED color", WhiteAccurate, warmWhite, 0xffc864, 0xff000000},
+		{"accurate gray", WhiteAccurate, warmWhite, 0x808080, 0x80001c4e},
+		{"accurate without blue", WhiteAccurate, ColorCorrection{255, 255, 0, 255}, 0x804020, 0x40400020},
+		{"accurate of red", WhiteAccurate, warmWhite, 0xff0000, 0xff0000},
+		{"brightness", WhiteBrightness, pureWhite, 0x804020, 0x80804020},
+		{"brightness ignores white", WhiteBrightness, pureWhite, 0x10804020, 0x80804020},
+		{"brightness of black", WhiteBrightness, pureWhite, 0, 0},
+	}
+	for _, test := range tests {
+		if got := extractWhite(test.led, test.mode, test.whiteColor); got != test.want {
+			t.Errorf("%s: %x: got %08x want %08x", test.name, test.led, got, test.want)
+		}
+	}
+}
+
+func TestWhiteModeRender(t *testing.T) {
+	strip := NewLEDStrip(2)
+	strip.SetDirect(0, 0xff804020)
+	strip.SetDirect(1, 0x00ffc864)
+	c, backend := newPWMTestConfig(t, strip, SK6812StripGRBW)
+	if c.channels[0].whiteColor != kelvinToCorrection(TemperatureWhiteLED) {
+		t.Errorf("default white color: got %v want %v", c.channels[0].whiteColor, kelvinToCorrection(TemperatureWhiteLED))
+	}
+	tests := []struct {
+		name  string
+		setup func() error
+		want  []uint32
+	}{
+		{"none", func() error { return nil }, []uint32{0xff804020, 0x00ffc864}},
+		{"min", func() error { return c.SetWhiteMode(WhiteMin, 0) }, []uint32{0x20602000, 0x649b6400}},
+		{"brightness", func() error { return c.SetWhiteMode(WhiteBrightness, 0) }, []uint32{0x80804020, 0xffffc864}},
+		//6600 K is pure white
+		{"accurate", func() error {
+			err := c.SetWhiteTemperature(6600, 0)
+			if err != nil {
+				return err
+			}
+			return c.SetWhiteMode(WhiteAccurate, 0)
+		}, []uint32{0x20602000, 0x649b6400}},
+		{"none again", func() error { return c.SetWhiteMode(WhiteNone, 0) }, []uint32{0xff804020, 0x00ffc864}},
+	}
+	for _, test := range tests {
+		err := test.setup()
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		decoded := renderDecoded(t, c, backend)
+		for i, want := range test.want {
+			if got := decoded[0].UInt32(i); got != want {
+				t.Errorf("%s: LED %d: got %08x want %08x", test.name, i, got, want)
+			}
+		}
+	}
+}
+
+func TestWhiteModeErrors(t *testing.T) {
+	c, _ := New(DriverPWM)
+	err := c.SetStrip(NewLEDStrip(1), 18, SK6812StripGRBW, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"mode", c.SetWhiteMode(WhiteBrightness+1, 0), ErrWrongWhiteMode},
+		{"mode index", c.SetWhiteMode(WhiteMin, 2), ErrConfigWrongIndex},
+		{"temperature too low", c.SetWhiteTemperature(999, 0), ErrWrongTemperature},
+		{"temperature too high", c.SetWhiteTemperature(40001, 0), ErrWrongTemperature},
+		{"temperature index", c.SetWhiteTemperature(TemperatureWhiteLED, -1), ErrConfigWrongIndex},
+	}
+	for _, test := range tests {
+		if errors.Cause(test.err) != test.want {
+			t.Errorf("%s: got %v want %v", test.name, test.err, test.want)
+		}
+	}
+	err = c.SetWhiteTemperature(2850, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.channels[0].whiteColor != kelvinToCorrection(2850) {
+		t.Errorf("got white color %v want %v", c.channels[0].whiteColor, kelvinToCorrection(2850))
+	}
+}
@@ -0,0 +1,135 @@
+package wled
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/DerLukas15/rpiws281x"
+)
+
+const jsonStatePath = "/json/state"
+
+//JSON of /json/state. Only the fields of the subset are used.
+type jsonState struct {
+	On         json.RawMessage `json:"on,omitempty"` // true, false or "t" to toggle
+	Brightness *int            `json:"bri,omitempty"`
+	Segments   json.RawMessage `json:"seg,omitempty"` // A segment or a list of segments. Only the first is used
+}
+
+//segment of jsonState
+type jsonSegment struct {
+	ID     int     `json:"id"`
+	Colors [][]int `json:"col,omitempty"` // Only the primary color is used
+	Effect *int    `json:"fx,omitempty"`
+}
+
+//state as returned by GET
+type jsonStateReply struct {
+	On         bool          `json:"on"`
+	Brightness int           `json:"bri"`
+	Segments   []jsonSegment `json:"seg"`
+}
+
+//ServeHTTP serves /json/state. GET returns the state. POST changes the fields on, bri and the primary color (col)
+//and the effect (fx) of the first segment.
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != jsonStatePath && req.URL.Path != jsonStatePath+"/" {
+		http.NotFound(w, req)
+		return
+	}
+	switch req.Method {
+	case http.MethodGet:
+		writeJSON(w, stateReply(s.State()))
+	case http.MethodPost:
+		var update jsonState
+		err := json.NewDecoder(req.Body).Decode(&update)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		state, ok := applyJSONState(s.state, update)
+		if !ok || state.Effect < 0 || state.Effect >= len(Effects) {
+			s.mu.Unlock()
+			http.Error(w, "invalid state", http.StatusBadRequest)
+			return
+		}
+		err = s.setState(state)
+		s.mu.Unlock()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]bool{"success": true})
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+//returns state changed by update. ok is false if update has invalid values
+func applyJSONState(state State, update jsonState) (State, bool) {
+	if len(update.On) > 0 {
+		if string(update.On) == `"t"` {
+			state.On = !state.On
+		} else if json.Unmarshal(update.On, &state.On) != nil {
+			return state, false
+		}
+	}
+	if update.Brightness != nil {
+		if *update.Brightness < 0 || *update.Brightness > 255 {
+			return state, false
+		}
+		state.Brightness = uint8(*update.Brightness)
+	}
+	if len(update.Segments) == 0 {
+		return state, true
+	}
+	var segment jsonSegment
+	if json.Unmarshal(update.Segments, &segment) != nil {
+		var segments []jsonSegment
+		if json.Unmarshal(update.Segments, &segments) != nil {
+			return state, false
+		}
+		if len(segments) == 0 {
+			return state, true
+		}
+		segment = segments[0]
+	}
+	if len(segment.Colors) > 0 && len(segment.Colors[0]) > 0 {
+		var color uint32
+		//Order red, green, blue and white
+		shifts := []uint{16, 8, 0, 24}
+		for i, curValue := range segment.Colors[0] {
+			if i >= len(shifts) || curValue < 0 || curValue > 255 {
+				return state, false
+			}
+			color |= uint32(curValue) << shifts[i]
+		}
+		state.Color = rpiws281x.SingleLED(color)
+	}
+	if segment.Effect != nil {
+		state.Effect = *segment.Effect
+	}
+	return state, true
+}
+
+//returns the JSON of state
+func stateReply(state State) jsonStateReply {
+	color := uint32(state.Color)
+	effect := state.Effect
+	return jsonStateReply{
+		On:         state.On,
+		Brightness: int(state.Brightness),
+		Segments: []jsonSegment{{
+			Colors: [][]int{{int(color >> 16 & 0xff), int(color >> 8 & 0xff), int(color & 0xff), int(color >> 24)}},
+			Effect: &effect,
+		}},
+	}
+}
+
+//writes v as JSON response
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,104 @@
+package wled
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/DerLukas15/rpiws281x"
+	"github.com/DerLukas15/rpiws281x/internal/receive"
+	"github.com/pkg/errors"
+)
+
+//Realtime protocols selected by the first byte of a packet
+const (
+	protocolWARLS = 1 // Index and RGB per LED. Up to 255 LEDs
+	protocolDRGB  = 2 // RGB from the first LED
+	protocolDRGBW = 3 // RGBW from the first LED
+	protocolDNRGB = 4 // 16 bit start index and RGB
+
+	realtimeHeaderSize = 2   // Protocol and timeout
+	timeoutInfinite    = 255 // Realtime data is shown until the state changes
+	maxPacketSize      = 1500
+)
+
+//HandleRealtime processes one realtime UDP packet and renders the written strips. Unknown protocols are ignored.
+//An error is only returned if Render fails.
+func (s *Server) HandleRealtime(packet []byte) error {
+	if len(packet) < realtimeHeaderSize {
+		return nil
+	}
+	data := packet[realtimeHeaderSize:]
+	var set receive.RenderSet
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch packet[0] {
+	case protocolWARLS:
+		for i := 0; i+4 <= len(data); i += 4 {
+			s.setSlots(&set, int(data[i]), data[i+1:i+4], 3)
+		}
+	case protocolDRGB:
+		s.setSlots(&set, 0, data, 3)
+	case protocolDRGBW:
+		s.setSlots(&set, 0, data, 4)
+	case protocolDNRGB:
+		if len(data) < 2 {
+			return nil
+		}
+		s.setSlots(&set, int(data[0])<<8|int(data[1]), data[2:], 3)
+	default:
+		return nil
+	}
+	s.realtime = true
+	s.realtimeUntil = time.Time{}
+	if packet[1] != timeoutInfinite {
+		timeout := time.Duration(packet[1]) * time.Second
+		if timeout == 0 {
+			timeout = time.Second
+		}
+		s.realtimeUntil = time.Now().Add(timeout)
+	}
+	err := set.Render(s.config)
+	if err != nil {
+		return errors.Wrap(err, "server realtime")
+	}
+	return nil
+}
+
+//writes the slots to the strips starting at LED position and adds the written strips to set
+func (s *Server) setSlots(set *receive.RenderSet, position int, slots []byte, colorCount int) {
+	end := position + len(slots)/colorCount
+	for _, curStrip := range s.strips {
+		stripEnd := curStrip.start + curStrip.leds.TotalCount()
+		if position >= stripEnd || end <= curStrip.start {
+			continue
+		}
+		first := position
+		if first < curStrip.start {
+			first = curStrip.start
+		}
+		rpiws281x.SetSlots(curStrip.leds, first-curStrip.start, slots[(first-position)*colorCount:], colorCount)
+		set.Add(curStrip.stripIndex)
+	}
+}
+
+//ServeRealtime handles the realtime packets received on conn until ctx is done or an error occurs. conn is not closed.
+//A done ctx is not an error.
+func (s *Server) ServeRealtime(ctx context.Context, conn net.PacketConn) error {
+	stop := receive.UnblockReads(ctx, conn)
+	defer stop()
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "server serve realtime")
+		}
+		err = s.HandleRealtime(buf[:n])
+		if err != nil {
+			return errors.Wrap(err, "server serve realtime")
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package wled
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/DerLukas15/rpiws281x"
+	"github.com/pkg/errors"
+)
+
+//a strip of the Server
+type strip struct {
+	stripIndex int
+	leds       rpiws281x.WritableLEDs
+	start      int // First LED in the realtime protocols
+}
+
+//Server controls the strips of a Config with the WLED realtime protocols and the JSON API.
+/*
+The strips are placed one after the other for the realtime protocols. Realtime data is shown with the brightness of the
+State until the timeout of the packet. Then the effect of the State is shown again.
+
+All methods are safe for concurrent use. Render is only called by the Server while it is used, so use State and SetState instead
+of writing to the strips.
+*/
+type Server struct {
+	config        *rpiws281x.Config
+	mu            sync.Mutex // Protects all fields, the strips and Render
+	strips        []strip
+	state         State
+	changed       bool // The state changed since the last frame
+	frame         uint64
+	realtime      bool
+	realtimeUntil time.Time // Zero for no timeout
+}
+
+//NewServer returns a Server for all strips of config which are set with SetStrip. The state is on with full brightness
+//and the solid effect in white. The brightness of the strips is set on config.
+func NewServer(config *rpiws281x.Config) (*Server, error) {
+	s := &Server{
+		config: config,
+		state: State{
+			On:         true,
+			Brightness: 255,
+			Color:      0x00ffffff,
+		},
+		changed: true,
+	}
+	start := 0
+	for stripIndex := 0; ; stripIndex++ {
+		leds, _, err := config.Strip(stripIndex)
+		if errors.Cause(err) == rpiws281x.ErrConfigWrongIndex {
+			break
+		}
+		if err != nil {
+			continue
+		}
+		writable, ok := leds.(rpiws281x.WritableLEDs)
+		if !ok {
+			return nil, errors.Wrap(ErrNotWritable, "new server")
+		}
+		s.strips = append(s.strips, strip{
+			stripIndex: stripIndex,
+			leds:       writable,
+			start:      start,
+		})
+		start += writable.TotalCount()
+	}
+	if len(s.strips) == 0 {
+		return nil, errors.Wrap(ErrNoStrips, "new server")
+	}
+	err := s.setState(s.state)
+	if err != nil {
+		return nil, errors.Wrap(err, "new server")
+	}
+	return s, nil
+}
+
+//State returns the current state.
+func (s *Server) State() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+//SetState sets the state which is shown with the next frame. The brightness is set on the Config immediately.
+func (s *Server) SetState(state State) error {
+	if state.Effect < 0 || state.Effect >= len(Effects) {
+		return errors.Wrap(ErrWrongEffect, "server SetState")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.setState(state)
+}
+
+//sets the state and the brightness of the strips
+func (s *Server) setState(state State) error {
+	brightness := uint32(state.Brightness)
+	if !state.On {
+		brightness = 0
+	}
+	for _, curStrip := range s.strips {
+		err := s.config.SetBrightness(brightness, curStrip.stripIndex)
+		if err != nil {
+			return errors.Wrap(err, "server set state")
+		}
+	}
+	if state.Effect != s.state.Effect {
+		s.frame = 0
+	}
+	s.state = state
+	s.changed = true
+	s.realtime = false
+	return nil
+}
+
+//returns the stripIndex to render all strips
+func (s *Server) renderIndex() int {
+	if len(s.strips) == 1 {
+		return s.strips[0].stripIndex
+	}
+	return -1
+}
+
+//draws and renders the next frame of the effect unless realtime data is shown. Static effects are only rendered after a change.
+func (s *Server) nextFrame(now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.realtime {
+		if s.realtimeUntil.IsZero() || now.Before(s.realtimeUntil) {
+			return nil
+		}
+		s.realtime = false
+		s.changed = true
+	}
+	if s.state.Effect == 0 && !s.changed {
+		return nil
+	}
+	s.changed = false
+	effect := Effects[s.state.Effect]
+	for _, curStrip := range s.strips {
+		effect(curStrip.leds, s.frame, s.state.Color)
+	}
+	s.frame++
+	err := s.config.Render(s.renderIndex())
+	if err != nil {
+		return errors.Wrap(err, "server frame")
+	}
+	return nil
+}
+
+//Run shows the effects with EffectFPS until ctx is done or Render fails. A done ctx is not an error.
+func (s *Server) Run(ctx context.Context) error {
+	ticker := time.NewTicker(time.Second / EffectFPS)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			err := s.nextFrame(now)
+			if err != nil {
+				return errors.Wrap(err, "server run")
+			}
+		}
+	}
+}
+
+//ListenAndServe runs the effects, listens for realtime packets on RealtimePort and serves the JSON API on the TCP address
+//httpAddr until ctx is done or an error occurs. DefaultHTTPAddress is used if httpAddr is empty. A done ctx is not an error.
+func (s *Server) ListenAndServe(ctx context.Context, httpAddr string) error {
+	if httpAddr == "" {
+		httpAddr = DefaultHTTPAddress
+	}
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{Port: RealtimePort})
+	if err != nil {
+		return errors.Wrap(err, "server listen")
+	}
+	defer conn.Close()
+	l, err := net.Listen("tcp", httpAddr)
+	if err != nil {
+		return errors.Wrap(err, "server listen")
+	}
+	httpServer := &http.Server{Handler: s}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, 3)
+	go func() {
+		errs <- s.Run(ctx)
+	}()
+	go func() {
+		errs <- s.ServeRealtime(ctx, conn)
+	}()
+	go func() {
+		err := httpServer.Serve(l)
+		if err == http.ErrServerClosed {
+			err = nil
+		}
+		errs <- err
+	}()
+	//The first error or the done ctx stops everything
+	select {
+	case err = <-errs:
+	case <-ctx.Done():
+	}
+	cancel()
+	httpServer.Close()
+	if err != nil {
+		return errors.Wrap(err, "server listen")
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package wled
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DerLukas15/rpiws281x"
+	"github.com/DerLukas15/rpiws281x/internal/receivetest"
+)
+
+//returns the colors of the last transfer per strip with the brightness applied
+func sentColors(t *testing.T, setup *receivetest.Setup) [][]uint32 {
+	t.Helper()
+	decoded, err := rpiws281x.DecodePWM(setup.Backend.LastTransfer(receivetest.DMAChannel), setup.Config.PWMLayout())
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := make([][]uint32, len(decoded))
+	for i, curStrip := range decoded {
+		for position := 0; position < curStrip.TotalCount(); position++ {
+			res[i] = append(res[i], curStrip.UInt32(position))
+		}
+	}
+	return res
+}
+
+//sends packet over the loopback interface and handles it with s
+func deliver(t *testing.T, s *Server, sender net.PacketConn, receiver net.PacketConn, packet []byte) {
+	t.Helper()
+	data, _ := receivetest.Transfer(t, sender, receiver, packet)
+	err := s.HandleRealtime(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+//returns a Server for the strips of c
+func newTestServer(t *testing.T, c *rpiws281x.Config) *Server {
+	t.Helper()
+	s, err := NewServer(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+//calls nextFrame with now and fails on errors
+func nextFrame(t *testing.T, s *Server, now time.Time) {
+	t.Helper()
+	err := s.nextFrame(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+//sends a request to the JSON API of s and returns the recorded response
+func request(s *Server, method, path, body string) *httptest.ResponseRecorder {
+	res := httptest.NewRecorder()
+	s.ServeHTTP(res, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return res
+}
+
+func TestNewServer(t *testing.T) {
+	setup := receivetest.NewSetup(t)
+	s := newTestServer(t, setup.Config)
+	if len(s.strips) != 2 || s.strips[0].start != 0 || s.strips[1].start != 5 {
+		t.Errorf("got strips %+v", s.strips)
+	}
+	empty, _ := rpiws281x.New(rpiws281x.DriverPWM)
+	_, err := NewServer(empty)
+	if err == nil {
+		t.Error("config without strips accepted")
+	}
+}
+
+func TestHandleRealtime(t *testing.T) {
+	setup := receivetest.NewSetup(t)
+	s := newTestServer(t, setup.Config)
+	sender, receiver := receivetest.Loopback(t)
+	u := receivetest.Unrendered
+	//Index and RGB. LED 6 is the second LED of strip 1
+	deliver(t, s, sender, receiver, []byte{protocolWARLS, timeoutInfinite, 6, 1, 2, 3, 0, 4, 5, 6})
+	//16 bit start index. Across both strips
+	deliver(t, s, sender, receiver, []byte{protocolDNRGB, timeoutInfinite, 0, 4, 9, 9, 9, 8, 8, 8})
+	//Only the first strip is written
+	deliver(t, s, sender, receiver, []byte{protocolDRGB, timeoutInfinite, 0x11, 0x12, 0x13})
+	drgbw := []byte{protocolDRGBW, timeoutInfinite}
+	drgbw = append(drgbw, make([]byte, 5*4)...)
+	drgbw = append(drgbw, 0x21, 0x22, 0x23, 0x24)
+	deliver(t, s, sender, receiver, drgbw)
+	//Ignored packets
+	deliver(t, s, sender, receiver, []byte{9, timeoutInfinite, 1, 2, 3})
+	deliver(t, s, sender, receiver, []byte{protocolDRGB})
+	deliver(t, s, sender, receiver, []byte{protocolDNRGB, timeoutInfinite, 0})
+	//After the last LED
+	deliver(t, s, sender, receiver, []byte{protocolWARLS, timeoutInfinite, 8, 1, 2, 3})
+	setup.CheckFrames(t, [][][]uint32{
+		{{0x040506, 0, 0, 0, 0}, {0, 0x010203, 0}},
+		{{0x040506, 0, 0, 0, 0x090909}, {0x080808, 0x010203, 0}},
+		{{0x111213, 0, 0, 0, 0x090909}, {u, u, u}},
+		{{0, 0, 0, 0, 0}, {0x24212223, 0x010203, 0}},
+	})
+}
+
+func TestRealtimeTimeout(t *testing.T) {
+	setup := receivetest.NewSetup(t)
+	s := newTestServer(t, setup.Config)
+	//The solid effect in white
+	now := time.Now()
+	nextFrame(t, s, now)
+	white := uint32(0xffffff)
+	u := receivetest.Unrendered
+	err := s.HandleRealtime([]byte{protocolDRGB, 2, 1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	//The realtime data is shown with the brightness of the state
+	if got := sentColors(t, setup)[0][0]; got != 0x010203 {
+		t.Errorf("got %x sent want 10203", got)
+	}
+	now = time.Now()
+	nextFrame(t, s, now)
+	nextFrame(t, s, now.Add(time.Second))
+	//Timed out. The effect is shown again
+	nextFrame(t, s, now.Add(3*time.Second))
+	err = s.HandleRealtime([]byte{protocolDRGB, timeoutInfinite, 4, 5, 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nextFrame(t, s, now.Add(time.Hour))
+	//A new state ends the realtime data
+	err = s.SetState(State{On: true, Brightness: 255, Color: 0x00ff0000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nextFrame(t, s, now.Add(time.Hour))
+	red := uint32(0xff0000)
+	setup.CheckFrames(t, [][][]uint32{
+		{{white, white, white, white, white}, {white, white, white}},
+		{{0x010203, white, white, white, white}, {u, u, u}},
+		{{white, white, white, white, white}, {white, white, white}},
+		{{0x040506, white, white, white, white}, {u, u, u}},
+		{{red, red, red, red, red}, {red, red, red}},
+	})
+}
+
+func TestNextFrame(t *testing.T) {
+	setup := receivetest.NewSetup(t)
+	s := newTestServer(t, setup.Config)
+	now := time.Now()
+	//The solid effect is only rendered after a change
+	nextFrame(t, s, now)
+	nextFrame(t, s, now)
+	if frames := setup.Frames(t); len(frames) != 1 {
+		t.Fatalf("got %d frames for the solid effect want 1", len(frames))
+	}
+	err := s.SetState(State{On: true, Brightness: 255, Color: 0x0000ff00, Effect: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < EffectFPS; i++ {
+		nextFrame(t, s, now)
+	}
+	frames := setup.Frames(t)[1:]
+	if len(frames) != EffectFPS {
+		t.Fatalf("got %d frames for the blink effect want %d", len(frames), EffectFPS)
+	}
+	//Off after half a second
+	for i, curFrame := range frames {
+		want := uint32(0x00ff00)
+		if i >= EffectFPS/2 {
+			want = 0
+		}
+		if curFrame[0][0] != want || curFrame[1][2] != want {
+			t.Errorf("frame %d: got %x %x want %x", i, curFrame[0][0], curFrame[1][2], want)
+		}
+	}
+	err = s.SetState(State{Effect: len(Effects)})
+	if err == nil {
+		t.Error("wrong effect accepted")
+	}
+}
+
+func TestJSONState(t *testing.T) {
+	setup := receivetest.NewSetup(t)
+	s := newTestServer(t, setup.Config)
+	res := request(s, http.MethodGet, jsonStatePath, "")
+	if res.Code != http.StatusOK {
+		t.Fatalf("GET: got status %d", res.Code)
+	}
+	var reply jsonStateReply
+	err := json.NewDecoder(res.Body).Decode(&reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reply.On || reply.Brightness != 255 || len(reply.Segments) != 1 || len(reply.Segments[0].Colors) != 1 {
+		t.Fatalf("got state %+v", reply)
+	}
+	if color := reply.Segments[0].Colors[0]; len(color) != 4 || color[0] != 255 || color[1] != 255 || color[2] != 255 || color[3] != 0 {
+		t.Errorf("got color %v", color)
+	}
+	tests := []struct {
+		body string
+		want State
+	}{
+		{`{"on":true,"bri":100,"seg":[{"col":[[255,0,0,7]],"fx":2}]}`, State{On: true, Brightness: 100, Color: 0x07ff0000, Effect: 2}},
+		//A single segment
+		{`{"seg":{"col":[[0,0,255]],"fx":0}}`, State{On: true, Brightness: 100, Color: 0x000000ff}},
+		{`{"on":"t","bri":255}`, State{Brightness: 255, Color: 0x000000ff}},
+		{`{"on":"t"}`, State{On: true, Brightness: 255, Color: 0x000000ff}},
+		{`{"seg":[]}`, State{On: true, Brightness: 255, Color: 0x000000ff}},
+	}
+	for _, test := range tests {
+		res = request(s, http.MethodPost, jsonStatePath, test.body)
+		if res.Code != http.StatusOK {
+			t.Errorf("%s: got status %d", test.body, res.Code)
+			continue
+		}
+		if state := s.State(); state != test.want {
+			t.Errorf("%s: got %+v want %+v", test.body, state, test.want)
+		}
+	}
+	//The state is shown with the next frame
+	nextFrame(t, s, time.Now())
+	if got := sentColors(t, setup)[1][0]; got != 0xff {
+		t.Errorf("got %x sent want ff", got)
+	}
+	request(s, http.MethodPost, jsonStatePath, `{"on":false}`)
+	nextFrame(t, s, time.Now())
+	if got := sentColors(t, setup)[1][0]; got != 0 {
+		t.Errorf("got %x sent while off", got)
+	}
+	wrongBodies := []string{
+		`{"seg":[{"fx":3}]}`,
+		`{"bri":300}`,
+		`{"on":1}`,
+		`{"seg":[{"col":[[256,0,0]]}]}`,
+		`{"seg":[{"col":[[0,0,0,0,0]]}]}`,
+		`{"on":`,
+	}
+	for _, body := range wrongBodies {
+		res = request(s, http.MethodPost, jsonStatePath, body)
+		if res.Code != http.StatusBadRequest {
+			t.Errorf("%s: got status %d want %d", body, res.Code, http.StatusBadRequest)
+		}
+	}
+	if state := s.State(); state.On || state.Color != 0x000000ff {
+		t.Errorf("wrong requests changed the state to %+v", state)
+	}
+	if res = request(s, http.MethodPut, jsonStatePath, "{}"); res.Code != http.StatusMethodNotAllowed {
+		t.Errorf("PUT: got status %d", res.Code)
+	}
+	if res = request(s, http.MethodGet, "/json/info", ""); res.Code != http.StatusNotFound {
+		t.Errorf("other path: got status %d", res.Code)
+	}
+}
+
+func TestServeRealtime(t *testing.T) {
+	setup := receivetest.NewSetup(t)
+	s := newTestServer(t, setup.Config)
+	sender, receiver := receivetest.Loopback(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan error, 1)
+	go func() {
+		served <- s.ServeRealtime(ctx, receiver)
+	}()
+	sender.WriteTo([]byte{protocolDRGB, timeoutInfinite, 1, 2, 3}, receiver.LocalAddr())
+	sender.WriteTo([]byte{9}, receiver.LocalAddr())
+	sender.WriteTo([]byte{protocolWARLS, timeoutInfinite, 5, 4, 5, 6}, receiver.LocalAddr())
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ServeRealtime did not return")
+	}
+	u := receivetest.Unrendered
+	setup.CheckFrames(t, [][][]uint32{
+		{{0x010203, 0, 0, 0, 0}, {u, u, u}},
+		{{u, u, u, u, u}, {0x040506, 0, 0}},
+	})
+}
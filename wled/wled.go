@@ -0,0 +1,69 @@
+//Package wled implements the realtime UDP protocols and a subset of the JSON API of WLED for the strips of a rpiws281x.Config.
+package wled
+
+import (
+	"errors"
+	"math"
+
+	"github.com/DerLukas15/rpiws281x"
+)
+
+// Errors
+var (
+	ErrWrongEffect = errors.New("effect index out of range")
+	ErrNotWritable = errors.New("strip is not writable")
+	ErrNoStrips    = errors.New("no strips")
+)
+
+const (
+	RealtimePort       = 21324 // UDP port of the realtime protocols
+	DefaultHTTPAddress = ":80" // Default TCP address of the JSON API
+	EffectFPS          = 50    // Frame rate of effects
+)
+
+//Effect draws one frame of an effect with color onto leds. frame counts with EffectFPS since the effect started.
+type Effect func(leds rpiws281x.WritableLEDs, frame uint64, color rpiws281x.SingleLED)
+
+//Effects are selected by their index in the JSON API. The first effects have the same index as in WLED.
+//More effects can be appended before a Server is used.
+var Effects = []Effect{
+	EffectSolid,
+	EffectBlink,
+	EffectBreathe,
+}
+
+//EffectSolid sets all LEDs to color. WLED effect 0.
+func EffectSolid(leds rpiws281x.WritableLEDs, frame uint64, color rpiws281x.SingleLED) {
+	c := color.ToColor()
+	for i := 0; i < leds.TotalCount(); i++ {
+		leds.SetColor(i, c)
+	}
+}
+
+//EffectBlink switches all LEDs between color and off every half second. WLED effect 1.
+func EffectBlink(leds rpiws281x.WritableLEDs, frame uint64, color rpiws281x.SingleLED) {
+	if (frame/(EffectFPS/2))%2 != 0 {
+		color = 0
+	}
+	EffectSolid(leds, frame, color)
+}
+
+//EffectBreathe fades all LEDs between color and dark with a period of 4 seconds. WLED effect 2.
+func EffectBreathe(leds rpiws281x.WritableLEDs, frame uint64, color rpiws281x.SingleLED) {
+	phase := float64(frame%(4*EffectFPS)) / (4 * EffectFPS)
+	//Never completely dark
+	factor := uint32(math.Round((0.55 - 0.45*math.Cos(2*math.Pi*phase)) * 256))
+	var res rpiws281x.SingleLED
+	for shift := 0; shift < 32; shift += 8 {
+		res |= rpiws281x.SingleLED(((uint32(color)>>shift&0xff)*factor)>>8) << shift
+	}
+	EffectSolid(leds, frame, res)
+}
+
+//State is the state set by the JSON API.
+type State struct {
+	On         bool
+	Brightness uint8               // Set with SetBrightness on all strips
+	Color      rpiws281x.SingleLED // Primary color of the effect
+	Effect     int                 // Index in Effects
+}